@@ -0,0 +1,19 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/niksmo/cloud-integration/config"
+)
+
+// runConfig checks the config for CI, "config validate" exits
+// with code 2 and lists every problem when the config is invalid.
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "validate" {
+		fmt.Fprintln(os.Stderr, "usage: config validate [--config file] [config flags]")
+		os.Exit(2)
+	}
+	config.Load()
+	fmt.Println("config is valid")
+}
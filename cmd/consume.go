@@ -0,0 +1,54 @@
+package main
+
+import (
+	"os"
+
+	"github.com/niksmo/cloud-integration/config"
+	"github.com/niksmo/cloud-integration/internal/adapter"
+	"github.com/niksmo/cloud-integration/internal/adapter/kafka"
+	"github.com/spf13/pflag"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// runConsume prints the payments of the topic as JSON lines until the
+// process receives a termination signal. It never registers schemas.
+func runConsume(args []string) {
+	cmdLine := pflag.NewFlagSet("consume", pflag.ExitOnError)
+	// config flags are parsed by config.LoadSections
+	cmdLine.ParseErrorsWhitelist.UnknownFlags = true
+	fromBeginning := cmdLine.Bool(
+		"from-beginning", false, "print the whole topic, only new payments if false",
+	)
+	_ = cmdLine.Parse(args)
+
+	sigCtx, cancel := signalContext()
+	defer cancel()
+
+	cfg := config.LoadSections(config.SectionBroker | config.SectionRegistry)
+	initLogger(cfg.LogLevel)
+	cfg.Broker.SchemaReadOnly = true
+
+	offset := kgo.NewOffset().AtEnd()
+	if *fromBeginning {
+		offset = kgo.NewOffset().AtStart()
+	}
+	// no group, the topic is tailed without committed offsets
+	// and the application group keeps its partitions
+	kafkaCl := createKafkaClient(cfg,
+		kgo.ConsumeTopics(cfg.Broker.Topic),
+		kgo.ConsumeResetOffset(offset),
+	)
+	serialization := paymentSerialization(cfg.Broker.SerdeFormat)
+	serdeSR, _ := createSerdeSR(sigCtx, cfg, serialization)
+
+	consumer := kafka.NewConsumer(
+		kafka.ConsumerClientOpt(kafkaCl),
+		kafka.ConsumerReceiverOpt(adapter.NewPaymentsWriter(os.Stdout)),
+		kafka.ConsumerDecodeFnOpt(serdeSR.DecodeNew),
+		kafka.ConsumerNoCommitOpt(),
+	)
+	go consumer.Run(sigCtx)
+
+	<-sigCtx.Done()
+	consumer.Close()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/colinmarc/hdfs/v2"
+	"github.com/niksmo/cloud-integration/config"
+	"github.com/spf13/pflag"
+)
+
+const hdfsUsage = "usage: hdfs ls [dir...] | hdfs cat file... [config flags]"
+
+// runHDFS lists directories or prints files of the configured HDFS,
+// "ls" without paths lists the root where the payments are saved.
+func runHDFS(args []string) {
+	const op = "Main.runHDFS"
+
+	if len(args) == 0 || (args[0] != "ls" && args[0] != "cat") {
+		fmt.Fprintln(os.Stderr, hdfsUsage)
+		os.Exit(2)
+	}
+	action := args[0]
+
+	cmdLine := pflag.NewFlagSet("hdfs", pflag.ExitOnError)
+	// config flags are parsed by config.LoadSections
+	cmdLine.ParseErrorsWhitelist.UnknownFlags = true
+	_ = cmdLine.Parse(args[1:])
+	paths := cmdLine.Args()
+	if action == "cat" && len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, hdfsUsage)
+		os.Exit(2)
+	}
+
+	cfg := config.LoadSections(config.SectionHDFS)
+	initLogger(cfg.LogLevel)
+
+	cl := createHDFSClient(cfg.HDFS.Address, cfg.HDFS.User)
+	var err error
+	switch action {
+	case "ls":
+		if len(paths) == 0 {
+			paths = []string{"/"}
+		}
+		err = listHDFS(cl, os.Stdout, paths)
+	case "cat":
+		err = catHDFS(cl, os.Stdout, paths)
+	}
+	cl.Close()
+	if err != nil {
+		die(op, err)
+	}
+}
+
+// listHDFS prints the mode, size, modification time and path
+// of the files, directories are listed one level deep.
+func listHDFS(cl *hdfs.Client, w io.Writer, paths []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, p := range paths {
+		fi, err := cl.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			printFileInfo(tw, p, fi)
+			continue
+		}
+		entries, err := cl.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			printFileInfo(tw, path.Join(p, e.Name()), e)
+		}
+	}
+	return tw.Flush()
+}
+
+func printFileInfo(w io.Writer, p string, fi os.FileInfo) {
+	fmt.Fprintf(
+		w, "%s\t%d\t%s\t%s\n",
+		fi.Mode(), fi.Size(), fi.ModTime().Format(time.RFC3339), p,
+	)
+}
+
+func catHDFS(cl *hdfs.Client, w io.Writer, paths []string) error {
+	for _, p := range paths {
+		f, err := cl.Open(p)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
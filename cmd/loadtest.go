@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/niksmo/cloud-integration/config"
+	"github.com/niksmo/cloud-integration/internal/adapter/kafka"
+	"github.com/niksmo/cloud-integration/internal/loadtest"
+	"github.com/spf13/pflag"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// runLoadtest produces payments at the target rate, consumes them
+// back without a consumer group and prints the latency report.
+func runLoadtest(args []string) {
+	const op = "Main.runLoadtest"
+
+	cmdLine := pflag.NewFlagSet("loadtest", pflag.ExitOnError)
+	// config flags are parsed by config.LoadSections
+	cmdLine.ParseErrorsWhitelist.UnknownFlags = true
+	rate := cmdLine.Float64("rate", 100, "payments per second")
+	senders := cmdLine.Int("senders", 4, "concurrent senders")
+	duration := cmdLine.Duration("duration", 30*time.Second, "sending time")
+	drain := cmdLine.Duration("drain", 10*time.Second, "time to await consumed payments")
+	out := cmdLine.String("out", "loadtest.json", "JSON report file")
+	_ = cmdLine.Parse(args)
+
+	var problems []string
+	if *rate <= 0 {
+		problems = append(problems, "--rate must be positive")
+	}
+	if *senders <= 0 {
+		problems = append(problems, "--senders must be positive")
+	}
+	if *duration <= 0 {
+		problems = append(problems, "--duration must be positive")
+	}
+	if *drain < 0 {
+		problems = append(problems, "--drain is negative")
+	}
+	if *out == "" {
+		problems = append(problems, "--out is empty")
+	}
+	if len(problems) != 0 {
+		for _, p := range problems {
+			fmt.Fprintln(os.Stderr, p)
+		}
+		fmt.Fprintln(os.Stderr, "usage: loadtest [flags] [config flags]")
+		cmdLine.SetOutput(os.Stderr)
+		cmdLine.PrintDefaults()
+		os.Exit(2)
+	}
+
+	sigCtx, cancel := signalContext()
+	defer cancel()
+
+	cfg := config.LoadSections(config.SectionBroker | config.SectionRegistry)
+	initLogger(cfg.LogLevel)
+
+	start := time.Now()
+	// no group, the load test records are read without committed
+	// offsets and the application group keeps its partitions
+	kafkaCl := createKafkaClient(cfg,
+		kgo.ConsumeTopics(cfg.Broker.Topic),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AfterMilli(start.UnixMilli())),
+	)
+	serialization := paymentSerialization(cfg.Broker.SerdeFormat)
+	serdeSR, subjSchema := createSerdeSR(sigCtx, cfg, serialization)
+	producer := createProducer(cfg, kafkaCl, serdeSR, subjSchema, serialization)
+
+	runner := loadtest.New(producer, loadtest.Config{
+		Rate: *rate, Senders: *senders, Duration: *duration, Drain: *drain,
+	})
+	consumer := kafka.NewConsumer(
+		kafka.ConsumerClientOpt(kafkaCl),
+		kafka.ConsumerReceiverOpt(runner),
+		kafka.ConsumerDecodeFnOpt(serdeSR.DecodeNew),
+		kafka.ConsumerNoCommitOpt(),
+	)
+	consumeCtx, stopConsume := context.WithCancel(sigCtx)
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		consumer.Run(consumeCtx)
+	}()
+
+	report := runner.Run(sigCtx)
+	// the producer and the consumer share the client,
+	// it is closed once the consumer is stopped
+	stopConsume()
+	<-consumed
+	producer.Close()
+
+	fmt.Println()
+	if err := report.WriteTable(os.Stdout); err != nil {
+		die(op, err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		die(op, err)
+	}
+	defer f.Close()
+	if err := report.WriteJSON(f); err != nil {
+		die(op, err)
+	}
+	slog.Info("load test report is written", "file", *out)
+}
@@ -3,67 +3,81 @@ package main
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/colinmarc/hdfs/v2"
 	"github.com/niksmo/cloud-integration/config"
 	"github.com/niksmo/cloud-integration/internal/adapter"
+	"github.com/niksmo/cloud-integration/internal/adapter/health"
 	"github.com/niksmo/cloud-integration/internal/adapter/kafka"
+	"github.com/niksmo/cloud-integration/internal/adapter/registry"
+	"github.com/niksmo/cloud-integration/internal/adapter/security"
+	"github.com/niksmo/cloud-integration/internal/core/fraud"
+	"github.com/niksmo/cloud-integration/internal/core/pipeline"
+	"github.com/niksmo/cloud-integration/internal/core/port"
 	"github.com/niksmo/cloud-integration/internal/core/service"
+	"github.com/niksmo/cloud-integration/internal/core/validation"
+	"github.com/niksmo/cloud-integration/internal/core/window"
 	"github.com/niksmo/cloud-integration/pkg/schema"
 	"github.com/twmb/franz-go/pkg/kgo"
-	"github.com/twmb/franz-go/pkg/sasl/scram"
 	"github.com/twmb/franz-go/pkg/sr"
 )
 
 func main() {
-	sigCtx, cancel := signalContext()
-	defer cancel()
-
-	cfg := config.Load()
-
-	initLogger(cfg.LogLevel)
-	slog.Info("application is started")
-
-	kafkaCl := createKafkaClient(cfg)
-	serdeSR := createSerdeSR(sigCtx, cfg)
-	hdfsCl := createHDFSClient(cfg.HDFS.Address, cfg.HDFS.User)
-
-	producer := kafka.NewProducer(
-		kafka.ProducerClientOpt(kafkaCl),
-		kafka.ProducerEncodeFnOpt(serdeSR.Encode),
-	)
-
-	hdfsStorage := adapter.NewHDFStorage(
-		adapter.HDFSClientOpt(hdfsCl),
-	)
-
-	service := service.New(producer, hdfsStorage)
+	// the pipeline runs without a command too
+	cmd, args := "run", os.Args[1:]
+	if len(args) != 0 && !strings.HasPrefix(args[0], "-") {
+		cmd, args = args[0], args[1:]
+	}
 
-	consumer := kafka.NewConsumer(
-		kafka.ConsumerClientOpt(kafkaCl),
-		kafka.ConsumerReceiverOpt(service),
-		kafka.ConsumerDecodeFnOpt(serdeSR.Decode),
-	)
+	switch cmd {
+	case "run":
+		runPipeline()
+	case "produce":
+		runProduce(args)
+	case "consume":
+		runConsume(args)
+	case "schema":
+		runSchema(args)
+	case "hdfs":
+		runHDFS(args)
+	case "registry":
+		runRegistry(args)
+	case "loadtest":
+		runLoadtest(args)
+	case "config":
+		runConfig(args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+}
 
-	paymentsGen := adapter.NewPaymentsGenerator(service, cfg.PaymentsGenTick)
+const usage = `usage: cloud-integration [command] [--config file] [config flags]
 
-	go consumer.Run(sigCtx)
-	go paymentsGen.Run(sigCtx)
+commands:
+  run                        run the pipeline of the configured roles, the default
+  produce                    send a payment from flags or JSON lines from stdin
+  consume                    print the topic payments as JSON lines
+  schema register|get|diff   manage the payment schema in the registry
+  hdfs ls|cat                list and print HDFS files
+  registry                   serve the local schema registry stand-in
+  loadtest                   measure the producer to consumer latency
+  config validate            check the config
+  help                       print this help
 
-	<-sigCtx.Done()
-	producer.Close()
-	consumer.Close()
-	hdfsStorage.Close(func(err error) {
-		slog.Error("failed to close hdfs storage", "err", err)
-	})
-	slog.Info("application is stopped")
-}
+every config field is also a flag, e.g. --broker-topic, see README
+`
 
 func signalContext() (context.Context, context.CancelFunc) {
 	return signal.NotifyContext(
@@ -78,87 +92,521 @@ func initLogger(level slog.Leveler) {
 	slog.SetDefault(logger)
 }
 
-func createKafkaClient(cfg config.Config) *kgo.Client {
+func createKafkaClient(cfg config.Config, opts ...kgo.Opt) *kgo.Client {
 	const op = "Main.createKafkaClient"
 
-	tlsConfig := createTLSConfig(cfg.Broker.CARootCert)
-
-	auth := scram.Auth{
-		User: cfg.Broker.User,
-		Pass: cfg.Broker.Pass,
+	opts = append(
+		kafkaConnOpts(cfg),
+		append([]kgo.Opt{kgo.DefaultProduceTopic(cfg.Broker.Topic)}, opts...)...,
+	)
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		die(op, err)
 	}
+	return cl
+}
 
-	cl, err := kgo.NewClient(
-		kgo.SeedBrokers(cfg.Broker.SeedBrokers...),
-		kgo.DialTLSConfig(tlsConfig),
-		kgo.SASL(auth.AsSha512Mechanism()),
-		// producer
-		kgo.DefaultProduceTopicAlways(),
-		kgo.DefaultProduceTopic(cfg.Broker.Topic),
-		// consumer
+// kafkaConsumerOpts join the consumer group of the payments topic,
+// a client without them only produces.
+func kafkaConsumerOpts(cfg config.Config) []kgo.Opt {
+	return []kgo.Opt{
 		kgo.ConsumeTopics(cfg.Broker.Topic),
 		kgo.ConsumerGroup(cfg.Broker.ConsumerGroup),
 		kgo.DisableAutoCommit(),
-	)
+	}
+}
+
+// kafkaConnOpts are the broker address, TLS and SASL options
+// of the configured security protocol.
+func kafkaConnOpts(cfg config.Config) []kgo.Opt {
+	const op = "Main.kafkaConnOpts"
+
+	opts := []kgo.Opt{kgo.SeedBrokers(cfg.Broker.SeedBrokers...)}
+	protocol := cfg.Broker.SecurityProtocol
+
+	if security.UsesTLS(protocol) {
+		tlsConfig := createTLSConfig(cfg, cfg.Broker.TLSServerName)
+		opts = append(opts, kgo.DialTLSConfig(tlsConfig))
+	}
+
+	if security.UsesSASL(protocol) && strings.EqualFold(
+		cfg.Broker.SASLMechanism, security.MechanismOAuthBearer,
+	) {
+		opts = append(opts, kgo.SASL(createTokenSource(cfg).Mechanism()))
+	} else if security.UsesSASL(protocol) {
+		// every new connection reads the password again
+		passSource := brokerPassSource(cfg)
+		mechanism, err := security.Mechanism(
+			cfg.Broker.SASLMechanism, cfg.Broker.User,
+			func() (string, error) {
+				pass, err := passSource()
+				return pass.Value(), err
+			},
+		)
+		if err != nil {
+			die(op, err)
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+	return opts
+}
+
+// tokenSource is shared by the broker and registry clients,
+// so the process keeps one token refreshed in the background.
+var (
+	tokenSourceOnce sync.Once
+	tokenSource     *security.TokenSource
+)
+
+// createTokenSource fails fast when the client secret file is
+// unreadable, the token is refreshed until the process exits.
+func createTokenSource(cfg config.Config) *security.TokenSource {
+	const op = "Main.createTokenSource"
+
+	tokenSourceOnce.Do(func() {
+		o := cfg.Broker.OAuth
+		secretSource := o.ClientSecretSource()
+		if _, err := secretSource(); err != nil {
+			die(op, err)
+		}
+		tokenSource = security.NewTokenSource(security.ClientCredentials{
+			TokenURL: o.TokenURL,
+			ClientID: o.ClientID,
+			ClientSecret: func() (string, error) {
+				secret, err := secretSource()
+				return secret.Value(), err
+			},
+			Scopes:        o.Scopes,
+			RefreshBefore: o.RefreshBefore,
+		})
+		go tokenSource.Run(context.Background())
+	})
+	return tokenSource
+}
+
+// brokerPassSource fails fast when the password file is unreadable.
+func brokerPassSource(cfg config.Config) func() (config.Secret, error) {
+	const op = "Main.brokerPassSource"
+
+	passSource := cfg.Broker.PassSource()
+	if _, err := passSource(); err != nil {
+		die(op, err)
+	}
+	return passSource
+}
+
+func createProducer(
+	cfg config.Config,
+	cl kafka.ProducerClient,
+	serdeSR *sr.Serde,
+	ss sr.SubjectSchema,
+	s schema.Serialization,
+) kafka.Producer {
+	opts := []kafka.ProducerOpt{
+		kafka.ProducerClientOpt(cl),
+		kafka.ProducerEncodeFnOpt(serdeSR.Encode),
+		kafka.ProducerSchemaOpt(ss.Subject, ss.Version, s.ContentType),
+	}
+	if cfg.Broker.KeySchema {
+		opts = append(opts, kafka.ProducerKeyEncodeFnOpt(serdeSR.Encode))
+	}
+	return kafka.NewProducer(opts...)
+}
+
+func paymentSerialization(format string) schema.Serialization {
+	const op = "Main.paymentSerialization"
+
+	s, err := schema.PaymentSerialization(schema.Format(format))
 	if err != nil {
 		die(op, err)
 	}
-	return cl
+	return s
 }
 
 func createSerdeSR(
-	ctx context.Context, cfg config.Config,
-) *sr.Serde {
+	ctx context.Context, cfg config.Config, s schema.Serialization,
+) (*sr.Serde, sr.SubjectSchema) {
 	const op = "Main.createSerdeSR"
 
-	tlsConfig := createTLSConfig(cfg.Broker.CARootCert)
+	registrar := createRegistrar(cfg)
+	subject := paymentSubject(cfg, s)
+
+	ss, err := registrar.Register(ctx, subject, s.Schema)
+	if err != nil {
+		die(op, err)
+	}
 
-	cl, err := sr.NewClient(
+	serde := new(sr.Serde)
+	serde.Register(ss.ID, schema.PaymentV2{}, s.EncodingOpts()...)
+
+	if cfg.Broker.KeySchema {
+		registerKeySchema(ctx, cfg, registrar, serde)
+	}
+	lookupRefundSchema(ctx, cfg, registrar, serde)
+
+	if s.Format != schema.FormatAvro {
+		return serde, ss
+	}
+
+	// V1 records may still be in the topic, they are decoded
+	// with their own schema and migrated by the consumer.
+	ssV1, err := registrar.Lookup(ctx, subject, schema.PaymentSchemaV1)
+	if err != nil {
+		slog.Warn("payment schema V1 is not registered", "op", op, "err", err)
+		return serde, ss
+	}
+	serde.Register(
+		ssV1.ID,
+		schema.PaymentV1{},
+		sr.DecodeFn(schema.PaymentV1Codec.DecodeFn()),
+	)
+	return serde, ss
+}
+
+func createRegistrar(cfg config.Config) registry.Registrar {
+	return registry.NewRegistrar(
+		registry.RegistrarClientOpt(createSRClient(cfg)),
+		registry.RegistrarCompatibilityOpt(cfg.Broker.SchemaCompatibility),
+		registry.RegistrarReadOnlyOpt(cfg.Broker.SchemaReadOnly),
+	)
+}
+
+func createSRClient(cfg config.Config) *sr.Client {
+	const op = "Main.createSRClient"
+
+	opts := []sr.ClientOpt{
 		sr.URLs(cfg.Broker.SchemaRegistryURLs...),
-		sr.DialTLSConfig(tlsConfig),
-		sr.BasicAuth(cfg.Broker.User, cfg.Broker.Pass),
+	}
+	switch cfg.Broker.SchemaRegistryAuth {
+	case security.AuthBasic:
+		opts = append(
+			opts, sr.PreReq(basicAuth(cfg.Broker.User, brokerPassSource(cfg))),
+		)
+	case security.AuthBearer:
+		opts = append(opts, sr.PreReq(createTokenSource(cfg).BearerAuth))
+	}
+	// the local registry stand-in is served over plain http
+	if usesHTTPS(cfg.Broker.SchemaRegistryURLs) {
+		tlsConfig := createTLSConfig(cfg, cfg.Broker.SchemaRegistryTLSServerName)
+		opts = append(opts, sr.DialTLSConfig(tlsConfig))
+	}
+
+	cl, err := sr.NewClient(opts...)
+	if err != nil {
+		die(op, err)
+	}
+	return cl
+}
+
+// paymentSubject is the value subject of the payment schema.
+func paymentSubject(cfg config.Config, s schema.Serialization) string {
+	const op = "Main.paymentSubject"
+
+	subject, err := registry.SubjectName(
+		registry.SubjectNameStrategy(cfg.Broker.SubjectNameStrategy),
+		cfg.Broker.Topic, s.RecordName, false,
 	)
 	if err != nil {
 		die(op, err)
 	}
+	return subject
+}
 
-	subject := cfg.Broker.Topic + "-value"
+func registerKeySchema(
+	ctx context.Context,
+	cfg config.Config,
+	registrar registry.Registrar,
+	serde *sr.Serde,
+) {
+	const op = "Main.registerKeySchema"
 
-	ss, err := cl.CreateSchema(
-		ctx, subject, schema.PaymentSchemaV1,
+	s, err := schema.PaymentKeySerialization(
+		schema.Format(cfg.Broker.SerdeFormat),
 	)
 	if err != nil {
 		die(op, err)
 	}
 
-	serde := new(sr.Serde)
-	serde.Register(
-		ss.ID,
-		schema.PaymentV1{},
-		sr.EncodeFn(schema.PaymentV1AvroEncodeFn()),
-		sr.DecodeFn(schema.PaymentV1AvroDecodeFn()),
+	subject, err := registry.SubjectName(
+		registry.SubjectNameStrategy(cfg.Broker.SubjectNameStrategy),
+		cfg.Broker.Topic, s.RecordName, true,
 	)
-	return serde
+	if err != nil {
+		die(op, err)
+	}
+
+	ss, err := registrar.Register(ctx, subject, s.Schema)
+	if err != nil {
+		die(op, err)
+	}
+	serde.Register(ss.ID, schema.PaymentKey{}, s.EncodingOpts()...)
+}
+
+// lookupRefundSchema adds the refund schema to the serde, so refund
+// records of other producers are decoded. The topic name strategy
+// keeps one record type per topic, so refunds are only looked up
+// with the record strategies.
+func lookupRefundSchema(
+	ctx context.Context,
+	cfg config.Config,
+	registrar registry.Registrar,
+	serde *sr.Serde,
+) {
+	const op = "Main.lookupRefundSchema"
+
+	strategy := registry.SubjectNameStrategy(cfg.Broker.SubjectNameStrategy)
+	if strategy == registry.TopicNameStrategy || strategy == "" {
+		return
+	}
+
+	s, err := schema.RefundSerialization(
+		schema.Format(cfg.Broker.SerdeFormat),
+	)
+	if err != nil {
+		die(op, err)
+	}
+
+	subject, err := registry.SubjectName(
+		strategy, cfg.Broker.Topic, s.RecordName, false,
+	)
+	if err != nil {
+		die(op, err)
+	}
+
+	ss, err := registrar.Lookup(ctx, subject, s.Schema)
+	if err != nil {
+		slog.Debug("refund schema is not registered", "op", op, "err", err)
+		return
+	}
+	serde.Register(ss.ID, schema.Refund{}, s.EncodingOpts()...)
 }
 
-func createTLSConfig(CARootFilepath string) *tls.Config {
+// basicAuth reads the password for every registry request,
+// so a rotated password file is used without a restart.
+func basicAuth(
+	user string, passSource func() (config.Secret, error),
+) func(*http.Request) error {
+	return func(req *http.Request) error {
+		pass, err := passSource()
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(user, pass.Value())
+		return nil
+	}
+}
+
+func usesHTTPS(urls []string) bool {
+	for _, u := range urls {
+		if strings.HasPrefix(u, "https://") {
+			return true
+		}
+	}
+	return false
+}
+
+func createTLSConfig(cfg config.Config, serverName string) *tls.Config {
 	const op = "Main.createTLSConfig"
 
-	caRootPEM, err := os.ReadFile(CARootFilepath)
+	tlsConfig, err := security.TLSConfig{
+		CAFile:             cfg.Broker.CARootCert,
+		CertFile:           cfg.Broker.ClientCert,
+		KeyFile:            cfg.Broker.ClientKey,
+		ServerName:         serverName,
+		MinVersion:         cfg.Broker.TLSMinVersion,
+		InsecureSkipVerify: cfg.Broker.InsecureSkipVerify,
+	}.Build()
 	if err != nil {
 		die(op, err)
 	}
+	if tlsConfig.InsecureSkipVerify {
+		slog.Warn("TLS certificate verification is disabled", "op", op)
+	}
+	return tlsConfig
+}
+
+func createValidator(cfg config.Config) *validation.Validator {
+	const op = "Main.createValidator"
 
-	rootCAs := x509.NewCertPool()
-	if ok := rootCAs.AppendCertsFromPEM(caRootPEM); !ok {
-		err := fmt.Errorf("failed to parse CARootPEM: %q", CARootFilepath)
+	limits := make(map[string]validation.Limits, len(cfg.Validation.AmountLimits))
+	for currency, l := range cfg.Validation.AmountLimits {
+		limits[currency] = validation.Limits{Min: l.Min, Max: l.Max}
+	}
+	v, err := validation.FromConfig(validation.Config{
+		MinAmount:         cfg.Validation.MinAmount,
+		MaxAmount:         cfg.Validation.MaxAmount,
+		AmountLimits:      limits,
+		AllowedCurrencies: cfg.Validation.AllowedCurrencies,
+		NamePattern:       cfg.Validation.NamePattern,
+		NameMaxLength:     cfg.Validation.NameMaxLength,
+	})
+	if err != nil {
 		die(op, err)
 	}
+	return v
+}
 
-	return &tls.Config{
-		RootCAs:    rootCAs,
-		ClientAuth: tls.NoClientCert,
+// consumerServiceOpts are the service options of received payments,
+// rejected payments are saved to HDFS.
+func consumerServiceOpts(
+	cfg config.Config, cl *kgo.Client, hdfsStorage adapter.HDFSStorage,
+) []service.Opt {
+	opts := []service.Opt{service.RejectSinkOpt(hdfsStorage)}
+	if cfg.Aggregation.WindowSize != 0 {
+		opts = append(opts, aggregatorOpt(cfg, cl, hdfsStorage))
+	}
+	if len(cfg.Pipeline) != 0 {
+		opts = append(opts, service.StagesOpt(createStages(cfg, hdfsStorage)...))
+	}
+	if cfg.Fraud.AlertsTopic != "" {
+		opts = append(opts, fraudDetectorOpt(cfg, cl))
+	}
+	return opts
+}
+
+func aggregatorOpt(
+	cfg config.Config, cl *kgo.Client, hdfsStorage adapter.HDFSStorage,
+) service.Opt {
+	const op = "Main.aggregatorOpt"
+
+	if cfg.Aggregation.OutputTopic == "" {
+		die(op, errors.New("aggregation output topic is empty"))
+	}
+
+	aggregator := window.New(window.Config{
+		Size:            cfg.Aggregation.WindowSize,
+		Slide:           cfg.Aggregation.WindowSlide,
+		AllowedLateness: cfg.Aggregation.AllowedLateness,
+		MaxClockSkew:    cfg.Aggregation.MaxClockSkew,
+	})
+	aggregatesProducer := kafka.NewAggregatesProducer(
+		kafka.AggregatesProducerClientOpt(cl),
+		kafka.AggregatesProducerTopicOpt(cfg.Aggregation.OutputTopic),
+	)
+	return service.AggregatorOpt(aggregator, aggregatesProducer, hdfsStorage)
+}
+
+func fraudDetectorOpt(cfg config.Config, cl *kgo.Client) service.Opt {
+	const op = "Main.fraudDetectorOpt"
+
+	engine, err := fraud.FromConfig(fraud.Config{
+		ZScoreK:          cfg.Fraud.ZScoreK,
+		ZScoreMinSamples: cfg.Fraud.ZScoreMinSamples,
+		ZScoreSamples:    cfg.Fraud.ZScoreSamples,
+		VelocityLimit:    cfg.Fraud.VelocityLimit,
+		VelocityWindow:   cfg.Fraud.VelocityWindow,
+		DuplicateWindow:  cfg.Fraud.DuplicateWindow,
+		MaxPayers:        cfg.Fraud.MaxPayers,
+	})
+	if err != nil {
+		die(op, err)
+	}
+	alertsProducer := kafka.NewAlertsProducer(
+		kafka.AlertsProducerClientOpt(cl),
+		kafka.AlertsProducerTopicOpt(cfg.Fraud.AlertsTopic),
+	)
+	return service.FraudDetectorOpt(engine, alertsProducer)
+}
+
+func createStages(
+	cfg config.Config, hdfsStorage adapter.HDFSStorage,
+) []port.PipelineStage {
+	const op = "Main.createStages"
+
+	specs := make([]pipeline.StageSpec, 0, len(cfg.Pipeline))
+	for _, sc := range cfg.Pipeline {
+		specs = append(specs, pipeline.StageSpec(sc))
+	}
+	sinks := map[string]port.PaymentsStorage{
+		"hdfs":    hdfsStorage,
+		"discard": pipeline.Discard,
+	}
+	stages, err := pipeline.Build(specs, sinks)
+	if err != nil {
+		die(op, err)
+	}
+	return stages
+}
+
+func generatorOpts(cfg config.Config) []adapter.GeneratorOpt {
+	const op = "Main.generatorOpts"
+
+	if cfg.PaymentsGenScenario == "" {
+		return nil
+	}
+	sc, err := adapter.LoadScenario(cfg.PaymentsGenScenario)
+	if err != nil {
+		die(op, err)
+	}
+	return []adapter.GeneratorOpt{adapter.GeneratorScenarioOpt(sc)}
+}
+
+func createReplayer(
+	cfg config.Config, s port.PaymentSender, hdfsCl *hdfs.Client,
+) *adapter.PaymentsReplayer {
+	const op = "Main.createReplayer"
+
+	m := cfg.Replay.Mapping
+	opts := []adapter.ReplayOpt{
+		adapter.ReplaySpeedOpt(cfg.Replay.Speed),
+		adapter.ReplayMappingOpt(adapter.ReplayMapping{
+			ID:          m["id"],
+			Name:        m["name"],
+			Amount:      m["amount"],
+			Currency:    m["currency"],
+			CreatedAt:   m["created_at"],
+			Description: m["description"],
+			Status:      m["status"],
+		}),
+	}
+	if path, ok := strings.CutPrefix(cfg.Replay.Path, "hdfs://"); ok {
+		opts = append(opts, adapter.ReplayHDFSFileOpt(hdfsCl, path))
+	} else {
+		opts = append(opts, adapter.ReplayLocalFileOpt(cfg.Replay.Path))
+	}
+	if cfg.Replay.Format != "" {
+		opts = append(opts, adapter.ReplayFormatOpt(cfg.Replay.Format))
+	}
+	if cfg.Replay.Progress != 0 {
+		opts = append(opts, adapter.ReplayProgressOpt(cfg.Replay.Progress))
+	}
+	replayer, err := adapter.NewPaymentsReplayer(s, opts...)
+	if err != nil {
+		die(op, err)
+	}
+	return replayer
+}
+
+// createHealthServer checks the clients of the running roles,
+// consumer and hdfsStorage are nil without the consumer role and
+// hdfsCl is nil when HDFS is not used.
+func createHealthServer(
+	cfg config.Config,
+	kafkaCl *kgo.Client,
+	hdfsCl *hdfs.Client,
+	consumer *kafka.Consumer,
+	hdfsStorage *adapter.HDFSStorage,
+) *health.Server {
+	opts := []health.Opt{
+		health.AddrOpt(fmt.Sprintf(":%d", cfg.Health.Port)),
+		health.TimeoutOpt(cfg.Health.CheckTimeout),
+		health.CheckOpt("broker", health.BrokerMetadata(kafkaCl, cfg.Broker.Topic)),
+		health.CheckOpt("schema_registry", health.SchemaRegistry(createSRClient(cfg))),
+	}
+	if hdfsCl != nil {
+		opts = append(opts, health.CheckOpt("hdfs", health.HDFS(hdfsCl)))
+	}
+	if consumer != nil {
+		opts = append(
+			opts,
+			health.CheckOpt("consumer_group", health.GroupMember(kafkaCl)),
+			health.CheckOpt("last_poll", health.Age(consumer.LastPoll, cfg.Health.MaxPollAge)),
+			// an idle topic has no records, their age is only reported
+			health.CheckOpt("last_records", health.Age(consumer.LastRecords, 0)),
+			health.CheckOpt("last_save", health.Age(hdfsStorage.LastSave, cfg.Health.MaxSaveAge)),
+		)
 	}
+	return health.NewServer(opts...)
 }
 
 func createHDFSClient(address, user string) *hdfs.Client {
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/niksmo/cloud-integration/config"
+	"github.com/niksmo/cloud-integration/internal/adapter"
+	"github.com/niksmo/cloud-integration/internal/core/service"
+	"github.com/spf13/pflag"
+)
+
+// runProduce sends a payment built from the flags, or JSON lines from
+// stdin when --name is empty. Payments pass the configured validation,
+// the process exits with code 1 when any of them is not sent.
+func runProduce(args []string) {
+	const op = "Main.runProduce"
+
+	cmdLine := pflag.NewFlagSet("produce", pflag.ExitOnError)
+	// config flags are parsed by config.LoadSections
+	cmdLine.ParseErrorsWhitelist.UnknownFlags = true
+	id := cmdLine.String("id", "", "payment id, a new UUID if empty")
+	name := cmdLine.String("name", "", "payer name, JSON lines are read from stdin if empty")
+	amount := cmdLine.String("amount", "", "decimal amount in major units")
+	currency := cmdLine.String("currency", "RUB", "currency code")
+	status := cmdLine.String("status", "", "lifecycle status, created if empty")
+	description := cmdLine.String("description", "", "payment description")
+	_ = cmdLine.Parse(args)
+
+	sigCtx, cancel := signalContext()
+	defer cancel()
+
+	cfg := config.LoadSections(config.SectionBroker | config.SectionRegistry | config.SectionValidation)
+	initLogger(cfg.LogLevel)
+
+	kafkaCl := createKafkaClient(cfg)
+	serialization := paymentSerialization(cfg.Broker.SerdeFormat)
+	serdeSR, subjSchema := createSerdeSR(sigCtx, cfg, serialization)
+	producer := createProducer(cfg, kafkaCl, serdeSR, subjSchema, serialization)
+	service := service.New(
+		producer, nil, service.ValidatorOpt(createValidator(cfg)),
+	)
+
+	// a payment from the flags is one JSON line of the default mapping
+	source, in := "stdin", io.Reader(os.Stdin)
+	if *name != "" {
+		if *id == "" {
+			*id = uuid.NewString()
+		}
+		line, err := json.Marshal(map[string]string{
+			"id":          *id,
+			"name":        *name,
+			"amount":      *amount,
+			"currency":    *currency,
+			"status":      *status,
+			"description": *description,
+		})
+		if err != nil {
+			die(op, err)
+		}
+		source, in = "flags", bytes.NewReader(append(line, '\n'))
+	}
+
+	replayer, err := adapter.NewPaymentsReplayer(
+		service,
+		adapter.ReplayReaderOpt(source, in),
+		adapter.ReplayFormatOpt(adapter.ReplayJSONL),
+	)
+	if err != nil {
+		die(op, err)
+	}
+	stats, err := replayer.Run(sigCtx)
+	producer.Close()
+	if err != nil {
+		die(op, err)
+	}
+
+	if *name != "" && stats.Sent == 1 {
+		fmt.Printf("sent payment %s\n", *id)
+	} else {
+		fmt.Printf(
+			"sent %d, failed %d, skipped %d\n",
+			stats.Sent, stats.Failed, stats.Skipped,
+		)
+	}
+	if stats.Failed+stats.Skipped != 0 {
+		os.Exit(1)
+	}
+}
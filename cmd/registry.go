@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/adapter/localsr"
+	"github.com/spf13/pflag"
+)
+
+// runRegistry serves the local schema registry stand-in
+// until the process receives a termination signal.
+func runRegistry(args []string) {
+	const op = "Main.runRegistry"
+
+	cmdLine := pflag.NewFlagSet("registry", pflag.ExitOnError)
+	addr := cmdLine.String("addr", "localhost:8081", "listen address")
+	dir := cmdLine.String("dir", ".schema-registry", "data directory")
+	_ = cmdLine.Parse(args)
+
+	sigCtx, cancel := signalContext()
+	defer cancel()
+
+	initLogger(slog.LevelInfo)
+
+	srv, err := localsr.NewServer(*dir)
+	if err != nil {
+		die(op, err)
+	}
+
+	httpSrv := &http.Server{
+		Addr:              *addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-sigCtx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpSrv.Shutdown(ctx); err != nil {
+			slog.Error("failed to shutdown registry", "op", op, "err", err)
+		}
+	}()
+
+	slog.Info("local schema registry is started", "addr", *addr, "dir", *dir)
+	err = httpSrv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("registry server failed", "op", op, "err", err)
+		os.Exit(1)
+	}
+	slog.Info("local schema registry is stopped")
+}
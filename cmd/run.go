@@ -0,0 +1,132 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	"github.com/colinmarc/hdfs/v2"
+	"github.com/niksmo/cloud-integration/config"
+	"github.com/niksmo/cloud-integration/internal/adapter"
+	"github.com/niksmo/cloud-integration/internal/adapter/kafka"
+	"github.com/niksmo/cloud-integration/internal/core/lifecycle"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+	"github.com/niksmo/cloud-integration/internal/core/service"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// runPipeline runs the roles of the config until the
+// process receives a termination signal.
+func runPipeline() {
+	sigCtx, cancel := signalContext()
+	defer cancel()
+
+	cfg := config.Load()
+
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel)
+	initLogger(logLevel)
+	produce := cfg.HasRole(config.RoleProducer)
+	consume := cfg.HasRole(config.RoleConsumer)
+	slog.Info("application is started", "roles", cfg.Roles)
+
+	var consumerOpts []kgo.Opt
+	if consume {
+		consumerOpts = kafkaConsumerOpts(cfg)
+	}
+	kafkaCl := createKafkaClient(cfg, consumerOpts...)
+	serialization := paymentSerialization(cfg.Broker.SerdeFormat)
+	serdeSR, subjSchema := createSerdeSR(sigCtx, cfg, serialization)
+
+	// a producer reads HDFS only to replay a file from it
+	var hdfsCl *hdfs.Client
+	if consume || strings.HasPrefix(cfg.Replay.Path, "hdfs://") {
+		hdfsCl = createHDFSClient(cfg.HDFS.Address, cfg.HDFS.User)
+	}
+
+	serviceOpts := []service.Opt{
+		service.ValidatorOpt(createValidator(cfg)),
+		service.StateTrackerOpt(lifecycle.NewTracker(lifecycle.DefaultCapacity)),
+	}
+
+	var (
+		producer    *kafka.Producer
+		hdfsStorage *adapter.HDFSStorage
+		sendTo      port.PaymentProducer
+		saveTo      port.PaymentsStorage
+	)
+	if produce {
+		p := createProducer(cfg, kafkaCl, serdeSR, subjSchema, serialization)
+		producer, sendTo = &p, p
+	}
+	if consume {
+		s := adapter.NewHDFStorage(
+			adapter.HDFSClientOpt(hdfsCl),
+			adapter.HDFSRetryBackoffOpt(cfg.Tuning.HDFSRetryBackoff),
+		)
+		hdfsStorage, saveTo = &s, s
+		serviceOpts = append(serviceOpts, consumerServiceOpts(cfg, kafkaCl, s)...)
+	}
+	service := service.New(sendTo, saveTo, serviceOpts...)
+
+	var consumer *kafka.Consumer
+	if consume {
+		c := kafka.NewConsumer(
+			kafka.ConsumerClientOpt(kafkaCl),
+			kafka.ConsumerReceiverOpt(service),
+			kafka.ConsumerDecodeFnOpt(serdeSR.DecodeNew),
+			kafka.ConsumerBatchSizeOpt(cfg.Tuning.ConsumerBatchSize),
+			kafka.ConsumerRetryBackoffOpt(cfg.Tuning.ConsumerRetryBackoff),
+		)
+		consumer = &c
+		go consumer.Run(sigCtx)
+	}
+
+	var paymentsGen *adapter.PaymentsGenerator
+	switch {
+	case produce && cfg.Replay.Path != "":
+		replayer := createReplayer(cfg, service, hdfsCl)
+		go func() {
+			if _, err := replayer.Run(sigCtx); err != nil {
+				slog.Error("replay is stopped", "err", err)
+			}
+		}()
+	case produce:
+		opts := append(generatorOpts(cfg), adapter.GeneratorInjectProducerOpt(producer))
+		paymentsGen = adapter.NewPaymentsGenerator(service, cfg.PaymentsGenTick, opts...)
+		go paymentsGen.Run(sigCtx)
+	}
+
+	if cfg.Health.Port != 0 {
+		healthSrv := createHealthServer(cfg, kafkaCl, hdfsCl, consumer, hdfsStorage)
+		go func() {
+			if err := healthSrv.Run(sigCtx); err != nil {
+				slog.Error("health server is stopped", "err", err)
+			}
+		}()
+	}
+
+	config.Watch(cfg, func(prev, next config.Config) {
+		logLevel.Set(next.LogLevel)
+		if paymentsGen != nil && next.PaymentsGenTick != prev.PaymentsGenTick {
+			paymentsGen.SetTick(next.PaymentsGenTick)
+		}
+		if consumer != nil {
+			consumer.SetBatchSize(next.Tuning.ConsumerBatchSize)
+			consumer.SetRetryBackoff(next.Tuning.ConsumerRetryBackoff)
+			hdfsStorage.SetRetryBackoff(next.Tuning.HDFSRetryBackoff)
+		}
+	})
+
+	<-sigCtx.Done()
+	service.Close()
+	if producer != nil {
+		producer.Close()
+	}
+	if consumer != nil {
+		consumer.Close()
+		hdfsStorage.Close(func(err error) {
+			slog.Error("failed to close hdfs storage", "err", err)
+		})
+	}
+	slog.Info("application is stopped")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/niksmo/cloud-integration/config"
+	"github.com/spf13/pflag"
+)
+
+const schemaUsage = "usage: schema register|get|diff [--version n] [config flags]"
+
+// runSchema manages the payment value schema of the configured format
+// and subject name strategy. "schema diff" exits with code 1 when the
+// local schema differs from the latest registered one.
+func runSchema(args []string) {
+	const op = "Main.runSchema"
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, schemaUsage)
+		os.Exit(2)
+	}
+	action := args[0]
+	if action != "register" && action != "get" && action != "diff" {
+		fmt.Fprintln(os.Stderr, schemaUsage)
+		os.Exit(2)
+	}
+
+	cmdLine := pflag.NewFlagSet("schema", pflag.ExitOnError)
+	// config flags are parsed by config.LoadSections
+	cmdLine.ParseErrorsWhitelist.UnknownFlags = true
+	version := cmdLine.Int("version", 0, "version to get, the latest if zero")
+	_ = cmdLine.Parse(args[1:])
+
+	sigCtx, cancel := signalContext()
+	defer cancel()
+
+	cfg := config.LoadSections(config.SectionRegistry)
+	initLogger(cfg.LogLevel)
+
+	s := paymentSerialization(cfg.Broker.SerdeFormat)
+	registrar := createRegistrar(cfg)
+	subject := paymentSubject(cfg, s)
+
+	switch action {
+	case "register":
+		ss, err := registrar.Register(sigCtx, subject, s.Schema)
+		if err != nil {
+			die(op, err)
+		}
+		fmt.Printf("subject %s version %d id %d\n", ss.Subject, ss.Version, ss.ID)
+	case "get":
+		ss, err := registrar.Get(sigCtx, subject, *version)
+		if err != nil {
+			die(op, err)
+		}
+		fmt.Printf(
+			"subject %s version %d id %d\n%s\n",
+			ss.Subject, ss.Version, ss.ID, ss.Schema.Schema,
+		)
+	case "diff":
+		latest, diff, err := registrar.Diff(sigCtx, subject, s.Schema)
+		if err != nil {
+			die(op, err)
+		}
+		if diff == "" {
+			fmt.Printf("subject %s version %d is up to date\n", subject, latest.Version)
+			return
+		}
+		if latest.Version == 0 {
+			fmt.Printf("--- not registered\n+++ local\n%s", diff)
+		} else {
+			fmt.Printf("--- registered (version %d)\n+++ local\n%s", latest.Version, diff)
+		}
+		os.Exit(1)
+	}
+}
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"os"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,13 +15,66 @@ import (
 )
 
 type brokerConfig struct {
-	SeedBrokers        []string `mapstructure:"seed_brokers"`
-	Topic              string   `mapstructure:"topic"`
-	ConsumerGroup      string   `mapstructure:"consumer_group"`
-	CARootCert         string   `mapstructure:"ca_root_cert"`
-	User               string   `mapstructure:"user"`
-	Pass               string   `mapstructure:"pass"`
+	SeedBrokers   []string `mapstructure:"seed_brokers"`
+	Topic         string   `mapstructure:"topic"`
+	ConsumerGroup string   `mapstructure:"consumer_group"`
+	CARootCert    string   `mapstructure:"ca_root_cert"`
+	User          string   `mapstructure:"user"`
+	Pass          Secret   `mapstructure:"pass"`
+	// PassFile replaces Pass with the file content,
+	// the file is read again when it is rotated.
+	PassFile           string   `mapstructure:"pass_file"`
 	SchemaRegistryURLs []string `mapstructure:"schema_registry_urls"`
+	// SchemaCompatibility is the subject compatibility level
+	// set on startup, empty keeps the registry level.
+	SchemaCompatibility string `mapstructure:"schema_compatibility"`
+	// SchemaReadOnly disables schema registration,
+	// the schema must already be registered.
+	SchemaReadOnly bool `mapstructure:"schema_read_only"`
+	// SerdeFormat is one of avro, protobuf or json, empty is avro.
+	SerdeFormat string `mapstructure:"serde_format"`
+	// SubjectNameStrategy is one of topic, record or topic_record,
+	// empty is topic.
+	SubjectNameStrategy string `mapstructure:"subject_name_strategy"`
+	// KeySchema enables record keys with a registered key schema.
+	KeySchema bool `mapstructure:"key_schema"`
+	// SecurityProtocol is one of plaintext, ssl, sasl_plaintext
+	// or sasl_ssl, see the security package.
+	SecurityProtocol string `mapstructure:"security_protocol"`
+	// SASLMechanism is one of PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512.
+	SASLMechanism string `mapstructure:"sasl_mechanism"`
+	// ClientCert and ClientKey enable mutual TLS, CARootCert
+	// may be empty to use the system roots.
+	ClientCert         string `mapstructure:"client_cert"`
+	ClientKey          string `mapstructure:"client_key"`
+	TLSServerName      string `mapstructure:"tls_server_name"`
+	TLSMinVersion      string `mapstructure:"tls_min_version"`
+	InsecureSkipVerify bool   `mapstructure:"insecure_skip_verify"`
+	// SchemaRegistryAuth is basic or none. The registry uses the TLS
+	// options of the brokers except the server name.
+	SchemaRegistryAuth          string `mapstructure:"schema_registry_auth"`
+	SchemaRegistryTLSServerName string `mapstructure:"schema_registry_tls_server_name"`
+	// OAuth is used by the OAUTHBEARER mechanism
+	// and the bearer schema registry auth.
+	OAuth oauthConfig `mapstructure:"oauth"`
+}
+
+// oauthConfig is the OAuth 2.0 client credentials grant.
+type oauthConfig struct {
+	TokenURL     string `mapstructure:"token_url"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret Secret `mapstructure:"client_secret"`
+	// ClientSecretFile replaces ClientSecret with the file content,
+	// the file is read again when it is rotated.
+	ClientSecretFile string   `mapstructure:"client_secret_file"`
+	Scopes           []string `mapstructure:"scopes"`
+	// RefreshBefore is how long before the expiry a token is replaced.
+	RefreshBefore time.Duration `mapstructure:"refresh_before"`
+}
+
+// ClientSecretSource returns the client secret, see SecretSource.
+func (o oauthConfig) ClientSecretSource() func() (Secret, error) {
+	return SecretSource(o.ClientSecret, o.ClientSecretFile)
 }
 
 type hdfsConfig struct {
@@ -26,76 +82,357 @@ type hdfsConfig struct {
 	User    string `mapstructure:"user"`
 }
 
+// validationConfig enables optional payment rules, zero values
+// are not checked. Amounts are decimals in major units.
+type validationConfig struct {
+	MinAmount string `mapstructure:"min_amount"`
+	MaxAmount string `mapstructure:"max_amount"`
+	// AmountLimits override the amounts above for a currency.
+	AmountLimits      map[string]amountLimitsConfig `mapstructure:"amount_limits"`
+	AllowedCurrencies []string                      `mapstructure:"allowed_currencies"`
+	NamePattern       string                        `mapstructure:"name_pattern"`
+	NameMaxLength     int                           `mapstructure:"name_max_length"`
+}
+
+// amountLimitsConfig are decimals in major units of one currency.
+type amountLimitsConfig struct {
+	Min string `mapstructure:"min"`
+	Max string `mapstructure:"max"`
+}
+
+// aggregationConfig is disabled when WindowSize is zero, zero
+// WindowSlide makes tumbling windows.
+type aggregationConfig struct {
+	WindowSize      time.Duration `mapstructure:"window_size"`
+	WindowSlide     time.Duration `mapstructure:"window_slide"`
+	AllowedLateness time.Duration `mapstructure:"allowed_lateness"`
+	MaxClockSkew    time.Duration `mapstructure:"max_clock_skew"`
+	OutputTopic     string        `mapstructure:"output_topic"`
+}
+
+// PassSource returns the broker password, see SecretSource.
+func (b brokerConfig) PassSource() func() (Secret, error) {
+	return SecretSource(b.Pass, b.PassFile)
+}
+
+// fraudConfig is disabled when AlertsTopic is empty,
+// rules with zero parameters are disabled.
+type fraudConfig struct {
+	AlertsTopic      string        `mapstructure:"alerts_topic"`
+	ZScoreK          float64       `mapstructure:"zscore_k"`
+	ZScoreMinSamples int           `mapstructure:"zscore_min_samples"`
+	ZScoreSamples    int           `mapstructure:"zscore_samples"`
+	VelocityLimit    int           `mapstructure:"velocity_limit"`
+	VelocityWindow   time.Duration `mapstructure:"velocity_window"`
+	DuplicateWindow  time.Duration `mapstructure:"duplicate_window"`
+	MaxPayers        int           `mapstructure:"max_payers"`
+}
+
+// stageConfig is a built-in pipeline stage, see pipeline.StageSpec.
+type stageConfig struct {
+	Name       string            `mapstructure:"name"`
+	Type       string            `mapstructure:"type"`
+	Currencies []string          `mapstructure:"currencies"`
+	Statuses   []string          `mapstructure:"statuses"`
+	Keep       int               `mapstructure:"keep"`
+	Text       string            `mapstructure:"text"`
+	Routes     map[string]string `mapstructure:"routes"`
+}
+
+// replayConfig is disabled when Path is empty, paths with the hdfs://
+// prefix are read from HDFS. Zero Speed sends as fast as possible,
+// 1 keeps the original gaps between created_at of payments.
+type replayConfig struct {
+	Path     string            `mapstructure:"path"`
+	Format   string            `mapstructure:"format"`
+	Speed    float64           `mapstructure:"speed"`
+	Progress time.Duration     `mapstructure:"progress"`
+	Mapping  map[string]string `mapstructure:"mapping"`
+}
+
+// tuningConfig is applied without a restart when the config file
+// changes. Every consumed batch is saved as one HDFS file, so
+// ConsumerBatchSize also limits the size of the files.
+type tuningConfig struct {
+	ConsumerBatchSize    int           `mapstructure:"consumer_batch_size"`
+	ConsumerRetryBackoff time.Duration `mapstructure:"consumer_retry_backoff"`
+	HDFSRetryBackoff     time.Duration `mapstructure:"hdfs_retry_backoff"`
+}
+
+// Roles of the process, a producer generates or replays payments and
+// sends them to the topic, a consumer saves the topic to HDFS.
+const (
+	RoleProducer = "producer"
+	RoleConsumer = "consumer"
+)
+
+var Roles = []string{RoleProducer, RoleConsumer}
+
+// healthConfig is the HTTP server of /healthz and /readyz,
+// zero Port disables it. Zero MaxSaveAge only reports the age,
+// as nothing is saved while the topic is idle.
+type healthConfig struct {
+	Port         int           `mapstructure:"port"`
+	CheckTimeout time.Duration `mapstructure:"check_timeout"`
+	MaxPollAge   time.Duration `mapstructure:"max_poll_age"`
+	MaxSaveAge   time.Duration `mapstructure:"max_save_age"`
+}
+
 type Config struct {
-	LogLevel        slog.Level    `mapstructure:"log_level"`
+	LogLevel slog.Level `mapstructure:"log_level"`
+	// Roles lists the roles run by the process, empty runs all of them.
+	Roles           []string      `mapstructure:"roles"`
 	PaymentsGenTick time.Duration `mapstructure:"payments_gen_tick"`
-	Broker          brokerConfig  `mapstructure:"broker"`
-	HDFS            hdfsConfig    `mapstructure:"hdfs"`
+	// PaymentsGenScenario is an optional scenario file
+	// that replaces the payments_gen_tick rate.
+	PaymentsGenScenario string       `mapstructure:"payments_gen_scenario"`
+	Broker              brokerConfig `mapstructure:"broker"`
+	HDFS                hdfsConfig   `mapstructure:"hdfs"`
+	// Validation is optional.
+	Validation validationConfig `mapstructure:"validation"`
+	// Aggregation is optional.
+	Aggregation aggregationConfig `mapstructure:"aggregation"`
+	// Fraud is optional.
+	Fraud fraudConfig `mapstructure:"fraud"`
+	// Pipeline stages are optional.
+	Pipeline []stageConfig `mapstructure:"pipeline"`
+	// Replay is optional, it replaces the payments generator.
+	Replay replayConfig `mapstructure:"replay"`
+	// Tuning is optional.
+	Tuning tuningConfig `mapstructure:"tuning"`
+	// Health is optional.
+	Health healthConfig `mapstructure:"health"`
 }
 
+// HasRole reports whether the process runs the role.
+func (c Config) HasRole(role string) bool {
+	return len(c.Roles) == 0 || slices.Contains(c.Roles, role)
+}
+
+// defaultConfigFile is optional, other files must exist.
+const defaultConfigFile = "/config.yaml"
+
+// Load reads the config file and overrides its fields with
+// environment variables and flags, see bindSources. The process
+// exits with code 2 when the config is unreadable or invalid.
 func Load() Config {
-	viper.SetConfigFile(getConfigFilepath())
+	return loadChecked(Config.Validate)
+}
 
-	err := viper.ReadInConfig()
-	if err != nil {
-		die(err)
-	}
+// LoadSections is Load for commands that use only some
+// sections of the config, the other ones are not validated.
+func LoadSections(sections Section) Config {
+	return loadChecked(func(c Config) error {
+		return c.ValidateSections(sections)
+	})
+}
 
-	var cfg Config
-	err = viper.UnmarshalExact(&cfg)
+func loadChecked(validate func(Config) error) Config {
+	cfg, err := load(viper.GetViper(), os.Args[1:])
 	if err != nil {
 		die(err)
 	}
 
 	print(cfg)
 
+	if err := validate(cfg); err != nil {
+		dieInvalid(err)
+	}
 	return cfg
 }
 
-func getConfigFilepath() string {
+func load(v *viper.Viper, args []string) (Config, error) {
 	cmdLine := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
-	arg := cmdLine.String("config", "/config.yaml", "config file")
-	_ = cmdLine.Parse(os.Args[1:])
+	// subcommands parse their own flags
+	cmdLine.ParseErrorsWhitelist.UnknownFlags = true
+	configFile := cmdLine.String(
+		"config", defaultConfigFile, "config file, env CLOUD_CONFIG_FILE",
+	)
+	if err := bindSources(v, cmdLine); err != nil {
+		return Config{}, err
+	}
+	_ = cmdLine.Parse(args)
+
+	path := getConfigFilepath(cmdLine.Changed("config"), *configFile)
+	v.SetConfigFile(path)
+
+	err := v.ReadInConfig()
+	if err != nil && (path != defaultConfigFile || !errors.Is(err, fs.ErrNotExist)) {
+		return Config{}, err
+	}
+
+	var cfg Config
+	if err := v.UnmarshalExact(&cfg); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// getConfigFilepath prefers the flag to the environment variable.
+func getConfigFilepath(flagSet bool, flag string) string {
+	if flagSet {
+		return flag
+	}
 	env, ok := os.LookupEnv("CLOUD_CONFIG_FILE")
 	if ok {
 		return env
 	}
-	return *arg
+	return flag
 }
 
 func die(err error) {
-	fmt.Printf("failed to load config file: %v\n", err)
+	fmt.Fprintf(os.Stderr, "failed to load config file: %v\n", err)
+	os.Exit(2)
+}
+
+func dieInvalid(err error) {
+	fmt.Fprintln(os.Stderr, "invalid config:")
+	for line := range strings.SplitSeq(err.Error(), "\n") {
+		fmt.Fprintf(os.Stderr, "\t%s\n", line)
+	}
 	os.Exit(2)
 }
 
 func print(c Config) {
 	tamplate := `
 	LogLevel=%q
+	Roles=%q
 	PaymentsGenTick=%s
+	PaymentsGenScenario=%q
 	SeedBrokers=%q
 	Topic=%q
 	ConsumerGroup=%q
 	CARootCert=%q
 	User=%q
 	Pass=%q
+	PassFile=%q
 	SchemaRegistryURLs=%q
+	SchemaCompatibility=%q
+	SchemaReadOnly=%t
+	SerdeFormat=%q
+	SubjectNameStrategy=%q
+	KeySchema=%t
+	SecurityProtocol=%q
+	SASLMechanism=%q
+	ClientCert=%q
+	ClientKey=%q
+	TLSServerName=%q
+	TLSMinVersion=%q
+	InsecureSkipVerify=%t
+	SchemaRegistryAuth=%q
+	SchemaRegistryTLSServerName=%q
+	OAuthTokenURL=%q
+	OAuthClientID=%q
+	OAuthClientSecret=%q
+	OAuthClientSecretFile=%q
+	OAuthScopes=%q
+	OAuthRefreshBefore=%s
 	HDFSAddress=%q
 	HDFSUser=%q
+	ValidationMinAmount=%q
+	ValidationMaxAmount=%q
+	ValidationAmountLimits=%+v
+	ValidationAllowedCurrencies=%q
+	ValidationNamePattern=%q
+	ValidationNameMaxLength=%d
+	AggregationWindowSize=%s
+	AggregationWindowSlide=%s
+	AggregationAllowedLateness=%s
+	AggregationMaxClockSkew=%s
+	AggregationOutputTopic=%q
+	FraudAlertsTopic=%q
+	FraudZScoreK=%v
+	FraudZScoreMinSamples=%d
+	FraudZScoreSamples=%d
+	FraudVelocityLimit=%d
+	FraudVelocityWindow=%s
+	FraudDuplicateWindow=%s
+	FraudMaxPayers=%d
+	Pipeline=%+v
+	ReplayPath=%q
+	ReplayFormat=%q
+	ReplaySpeed=%v
+	ReplayProgress=%s
+	ReplayMapping=%v
+	TuningConsumerBatchSize=%d
+	TuningConsumerRetryBackoff=%s
+	TuningHDFSRetryBackoff=%s
+	HealthPort=%d
+	HealthCheckTimeout=%s
+	HealthMaxPollAge=%s
+	HealthMaxSaveAge=%s
 
 `
-	fmt.Println("Loaded config:")
-	fmt.Printf(
+	// stdout is left to the command output
+	fmt.Fprintln(os.Stderr, "Loaded config:")
+	fmt.Fprintf(
+		os.Stderr,
 		strings.TrimLeft(tamplate, "\n"),
 		c.LogLevel,
+		c.Roles,
 		c.PaymentsGenTick,
+		c.PaymentsGenScenario,
 		c.Broker.SeedBrokers,
 		c.Broker.Topic,
 		c.Broker.ConsumerGroup,
 		c.Broker.CARootCert,
 		c.Broker.User,
 		c.Broker.Pass,
+		c.Broker.PassFile,
 		c.Broker.SchemaRegistryURLs,
+		c.Broker.SchemaCompatibility,
+		c.Broker.SchemaReadOnly,
+		c.Broker.SerdeFormat,
+		c.Broker.SubjectNameStrategy,
+		c.Broker.KeySchema,
+		c.Broker.SecurityProtocol,
+		c.Broker.SASLMechanism,
+		c.Broker.ClientCert,
+		c.Broker.ClientKey,
+		c.Broker.TLSServerName,
+		c.Broker.TLSMinVersion,
+		c.Broker.InsecureSkipVerify,
+		c.Broker.SchemaRegistryAuth,
+		c.Broker.SchemaRegistryTLSServerName,
+		c.Broker.OAuth.TokenURL,
+		c.Broker.OAuth.ClientID,
+		c.Broker.OAuth.ClientSecret,
+		c.Broker.OAuth.ClientSecretFile,
+		c.Broker.OAuth.Scopes,
+		c.Broker.OAuth.RefreshBefore,
 		c.HDFS.Address,
 		c.HDFS.User,
+		c.Validation.MinAmount,
+		c.Validation.MaxAmount,
+		c.Validation.AmountLimits,
+		c.Validation.AllowedCurrencies,
+		c.Validation.NamePattern,
+		c.Validation.NameMaxLength,
+		c.Aggregation.WindowSize,
+		c.Aggregation.WindowSlide,
+		c.Aggregation.AllowedLateness,
+		c.Aggregation.MaxClockSkew,
+		c.Aggregation.OutputTopic,
+		c.Fraud.AlertsTopic,
+		c.Fraud.ZScoreK,
+		c.Fraud.ZScoreMinSamples,
+		c.Fraud.ZScoreSamples,
+		c.Fraud.VelocityLimit,
+		c.Fraud.VelocityWindow,
+		c.Fraud.DuplicateWindow,
+		c.Fraud.MaxPayers,
+		c.Pipeline,
+		c.Replay.Path,
+		c.Replay.Format,
+		c.Replay.Speed,
+		c.Replay.Progress,
+		c.Replay.Mapping,
+		c.Tuning.ConsumerBatchSize,
+		c.Tuning.ConsumerRetryBackoff,
+		c.Tuning.HDFSRetryBackoff,
+		c.Health.Port,
+		c.Health.CheckTimeout,
+		c.Health.MaxPollAge,
+		c.Health.MaxSaveAge,
 	)
 }
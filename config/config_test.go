@@ -0,0 +1,81 @@
+//go:build !integration
+
+package config
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+payments_gen_tick: 5s
+broker:
+  topic: file_topic
+  user: file_user
+  pass: file_pass
+hdfs:
+  address: file_address
+`), 0o600))
+
+	t.Setenv("CLOUD_BROKER_USER", "env_user")
+	t.Setenv("CLOUD_BROKER_PASS", "env_pass")
+	t.Setenv("CLOUD_BROKER_TOPIC", "env_topic")
+	t.Setenv("CLOUD_BROKER_SEED_BROKERS", "b1:9092,b2:9092")
+	t.Setenv("CLOUD_LOG_LEVEL", "-4")
+
+	cfg, err := load(viper.New(), []string{
+		"loadtest", "--config", path, "--broker-topic", "flag_topic", "--rate", "10",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "flag_topic", cfg.Broker.Topic)
+	assert.Equal(t, "env_user", cfg.Broker.User)
+	assert.Equal(t, "env_pass", cfg.Broker.Pass.Value())
+	assert.Equal(t, "file_address", cfg.HDFS.Address)
+	assert.Equal(t, []string{"b1:9092", "b2:9092"}, cfg.Broker.SeedBrokers)
+	assert.Equal(t, 5*time.Second, cfg.PaymentsGenTick)
+	assert.Equal(t, slog.LevelDebug, cfg.LogLevel)
+	// defaults
+	assert.Equal(t, "avro", cfg.Broker.SerdeFormat)
+	assert.Equal(t, 10*time.Second, cfg.Replay.Progress)
+}
+
+func TestSecretsHaveNoFlags(t *testing.T) {
+	cfg, err := load(viper.New(), []string{
+		"loadtest",
+		"--broker-pass", "flag_pass",
+		"--broker-oauth-client-secret", "flag_secret",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Broker.Pass.Value())
+	assert.Empty(t, cfg.Broker.OAuth.ClientSecret.Value())
+}
+
+func TestLoadWithoutDefaultFile(t *testing.T) {
+	if _, err := os.Stat(defaultConfigFile); err == nil {
+		t.Skip("default config file exists")
+	}
+	t.Setenv("CLOUD_HDFS_ADDRESS", "env_address")
+
+	cfg, err := load(viper.New(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "env_address", cfg.HDFS.Address)
+	assert.Equal(t, Roles, cfg.Roles)
+
+	t.Setenv("CLOUD_ROLES", "consumer")
+	cfg, err = load(viper.New(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{RoleConsumer}, cfg.Roles)
+
+	_, err = load(viper.New(), []string{"--config", "/missing.yaml"})
+	assert.Error(t, err)
+}
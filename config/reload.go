@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadable fields are applied without a restart, a key ending
+// with a dot covers the whole section.
+var reloadable = []string{"log_level", "payments_gen_tick", "tuning."}
+
+// Change of a config field, secrets are redacted.
+type Change struct {
+	Path string `json:"path"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// Watch applies changes of the config file read by Load. A change is
+// applied as a whole or rejected with a warning when the new config is
+// invalid or changes fields that need a restart. apply gets the
+// previous and the new config and is never called concurrently.
+func Watch(cur Config, apply func(prev, next Config)) {
+	r := &reloader{v: viper.GetViper(), cur: cur, apply: apply}
+	viper.OnConfigChange(func(fsnotify.Event) { r.reload() })
+	viper.WatchConfig()
+}
+
+type reloader struct {
+	mu    sync.Mutex
+	v     *viper.Viper
+	cur   Config
+	apply func(prev, next Config)
+}
+
+func (r *reloader) reload() {
+	const op = "config.reload"
+	log := slog.With("op", op)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var next Config
+	if err := r.v.UnmarshalExact(&next); err != nil {
+		log.Warn("config reload is rejected", "err", err)
+		return
+	}
+	if err := next.Validate(); err != nil {
+		log.Warn("config reload is rejected", "err", err)
+		return
+	}
+
+	changes := Diff(r.cur, next)
+	if len(changes) == 0 {
+		return
+	}
+	var restart []string
+	for _, c := range changes {
+		if !isReloadable(c.Path) {
+			restart = append(restart, c.Path)
+		}
+	}
+	if len(restart) != 0 {
+		log.Warn(
+			"config reload is rejected, fields need a restart",
+			"fields", restart, "changes", changes,
+		)
+		return
+	}
+
+	r.apply(r.cur, next)
+	r.cur = next
+	log.Info("config is reloaded", "changes", changes)
+}
+
+func isReloadable(path string) bool {
+	for _, key := range reloadable {
+		if path == key || (strings.HasSuffix(key, ".") && strings.HasPrefix(path, key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the changed fields by their config keys.
+func Diff(prev, next Config) []Change {
+	var changes []Change
+	diffStruct(reflect.ValueOf(prev), reflect.ValueOf(next), "", &changes)
+	return changes
+}
+
+func diffStruct(prev, next reflect.Value, prefix string, changes *[]Change) {
+	t := prev.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+		a, b := prev.Field(i), next.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			diffStruct(a, b, key+".", changes)
+			continue
+		}
+		if reflect.DeepEqual(a.Interface(), b.Interface()) {
+			continue
+		}
+		*changes = append(*changes, Change{
+			Path: key,
+			Old:  fmt.Sprint(a.Interface()),
+			New:  fmt.Sprint(b.Interface()),
+		})
+	}
+}
@@ -0,0 +1,81 @@
+//go:build !integration
+
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadYAML = `
+log_level: %d
+payments_gen_tick: 1s
+broker:
+  seed_brokers: [broker:9091]
+  topic: %s
+  consumer_group: group
+  ca_root_cert: %s
+  user: user
+  pass: pass
+  schema_registry_urls: [https://sr:443]
+hdfs:
+  address: hdfs:9000
+  user: hdfs
+`
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(ca, []byte("pem"), 0o600))
+	path := filepath.Join(dir, "config.yaml")
+	write := func(level int, topic string) {
+		data := fmt.Sprintf(reloadYAML, level, topic, ca)
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	}
+
+	write(0, "payments")
+	v := viper.New()
+	cur, err := load(v, []string{"--config", path})
+	require.NoError(t, err)
+
+	var applied []Config
+	r := &reloader{v: v, cur: cur, apply: func(_, next Config) {
+		applied = append(applied, next)
+	}}
+
+	write(-4, "payments")
+	require.NoError(t, v.ReadInConfig())
+	r.reload()
+	require.Len(t, applied, 1)
+	assert.Equal(t, slog.LevelDebug, applied[0].LogLevel)
+
+	// the topic needs a restart, the level change is not applied either
+	write(4, "other")
+	require.NoError(t, v.ReadInConfig())
+	r.reload()
+	assert.Len(t, applied, 1)
+	assert.Equal(t, slog.LevelDebug, r.cur.LogLevel)
+}
+
+func TestDiff(t *testing.T) {
+	prev := Config{PaymentsGenTick: time.Second}
+	prev.Broker.Pass = "old"
+	next := prev
+	next.PaymentsGenTick = 2 * time.Second
+	next.Broker.Pass = "new"
+
+	assert.Equal(t, []Change{
+		{Path: "payments_gen_tick", Old: "1s", New: "2s"},
+		{Path: "broker.pass", Old: redacted, New: redacted},
+	}, Diff(prev, next))
+	assert.True(t, isReloadable("tuning.hdfs_retry_backoff"))
+	assert.False(t, isReloadable("broker.pass"))
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Secret is a credential that is redacted when printed or logged,
+// Value returns the credential itself.
+type Secret string
+
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("%q", s.String())
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SecretSource returns the secret from the file when it is set, the file
+// is read again once it changes so rotated credentials are picked up
+// without a restart. Without the file the value is returned.
+func SecretSource(value Secret, file string) func() (Secret, error) {
+	if file == "" {
+		return func() (Secret, error) { return value, nil }
+	}
+
+	var (
+		mu      sync.Mutex
+		cached  Secret
+		modTime time.Time
+		size    int64 = -1
+	)
+	return func() (Secret, error) {
+		const op = "config.SecretSource"
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		fi, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		if fi.ModTime().Equal(modTime) && fi.Size() == size {
+			return cached, nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", op, err)
+		}
+		if size != -1 {
+			slog.Info("secret file is changed", "op", op, "file", file)
+		}
+		cached = Secret(strings.TrimRight(string(data), "\r\n"))
+		modTime, size = fi.ModTime(), fi.Size()
+		return cached, nil
+	}
+}
@@ -0,0 +1,51 @@
+//go:build !integration
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretRedacted(t *testing.T) {
+	s := Secret("hunter2")
+
+	assert.Equal(t, "hunter2", s.Value())
+	assert.Equal(t, `"[REDACTED]"`, fmt.Sprintf("%q", s))
+	assert.Equal(t, "{[REDACTED]}", fmt.Sprintf("%v", struct{ P Secret }{s}))
+	assert.NotContains(t, fmt.Sprintf("%#v", struct{ P Secret }{s}), "hunter2")
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("msg", "pass", s)
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Empty(t, Secret("").String())
+}
+
+func TestSecretSourceRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pass")
+	require.NoError(t, os.WriteFile(path, []byte("first\n"), 0o600))
+
+	get := SecretSource("from_config", path)
+	s, err := get()
+	require.NoError(t, err)
+	assert.Equal(t, "first", s.Value())
+
+	require.NoError(t, os.WriteFile(path, []byte("rotated\n"), 0o600))
+	s, err = get()
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", s.Value())
+
+	s, err = SecretSource("from_config", "")()
+	require.NoError(t, err)
+	assert.Equal(t, "from_config", s.Value())
+
+	_, err = SecretSource("", filepath.Join(t.TempDir(), "missing"))()
+	assert.Error(t, err)
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix of the environment variables, broker.pass is CLOUD_BROKER_PASS.
+const EnvPrefix = "CLOUD"
+
+// defaults of optional fields, the others are zero.
+var defaults = map[string]any{
+	"log_level":                     0,
+	"roles":                         Roles,
+	"broker.serde_format":           "avro",
+	"broker.subject_name_strategy":  "topic",
+	"broker.security_protocol":      "sasl_ssl",
+	"broker.sasl_mechanism":         "SCRAM-SHA-512",
+	"broker.schema_registry_auth":   "basic",
+	"broker.oauth.refresh_before":   time.Minute,
+	"fraud.max_payers":              10_000,
+	"replay.progress":               10 * time.Second,
+	"tuning.consumer_retry_backoff": time.Second,
+	"tuning.hdfs_retry_backoff":     time.Second,
+	"health.port":                   8080,
+	"health.check_timeout":          5 * time.Second,
+	"health.max_poll_age":           time.Minute,
+}
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	secretType   = reflect.TypeFor[Secret]()
+)
+
+// bindSources makes every scalar and list field of Config settable with
+// a flag and an environment variable. Viper resolves them as
+// flags > env > file > defaults. Lists of structs and maps, like
+// pipeline and replay.mapping, are set in the file only. Secrets have
+// no flags, a command line is seen by other users of the host.
+func bindSources(v *viper.Viper, fs *pflag.FlagSet) error {
+	for key, def := range defaults {
+		v.SetDefault(key, def)
+	}
+	return bindStruct(v, fs, reflect.TypeFor[Config](), "")
+}
+
+func bindStruct(v *viper.Viper, fs *pflag.FlagSet, t reflect.Type, prefix string) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := f.Tag.Get("mapstructure")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		key := prefix + tag
+
+		if f.Type.Kind() == reflect.Struct {
+			if err := bindStruct(v, fs, f.Type, key+"."); err != nil {
+				return err
+			}
+			continue
+		}
+
+		env := EnvName(key)
+		if f.Type == secretType {
+			if err := v.BindEnv(key, env); err != nil {
+				return err
+			}
+			continue
+		}
+		if !addFlag(fs, f.Type, FlagName(key), env, v.Get(key)) {
+			continue
+		}
+		if err := v.BindEnv(key, env); err != nil {
+			return err
+		}
+		if err := v.BindPFlag(key, fs.Lookup(FlagName(key))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFlag reports false for the types that have no flag.
+func addFlag(fs *pflag.FlagSet, t reflect.Type, name, env string, def any) bool {
+	usage := fmt.Sprintf("env %s", env)
+	switch {
+	case t == durationType:
+		d, _ := def.(time.Duration)
+		fs.Duration(name, d, usage)
+	case t.Kind() == reflect.String:
+		s, _ := def.(string)
+		fs.String(name, s, usage)
+	case t.Kind() == reflect.Bool:
+		b, _ := def.(bool)
+		fs.Bool(name, b, usage)
+	case t.Kind() == reflect.Int:
+		n, _ := def.(int)
+		fs.Int(name, n, usage)
+	case t.Kind() == reflect.Float64:
+		x, _ := def.(float64)
+		fs.Float64(name, x, usage)
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		fs.StringSlice(name, nil, usage)
+	default:
+		return false
+	}
+	return true
+}
+
+// EnvName is the environment variable of the config key.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// FlagName is the command line flag of the config key,
+// broker.seed_brokers is --broker-seed-brokers.
+func FlagName(key string) string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(key)
+}
@@ -0,0 +1,404 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/niksmo/cloud-integration/internal/adapter/security"
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/pipeline"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+	"github.com/niksmo/cloud-integration/pkg/replayfmt"
+	"github.com/niksmo/cloud-integration/pkg/schema"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+// FieldError is a problem with the config field at Path.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+var (
+	serdeFormats = []string{
+		string(schema.FormatAvro), string(schema.FormatProtobuf), string(schema.FormatJSON),
+	}
+	subjectNameStrategies = []string{"topic", "record", "topic_record"}
+	stageTypes            = []string{
+		pipeline.TypeFilterCurrency, pipeline.TypeFilterStatus, pipeline.TypeMaskName,
+		pipeline.TypeDefaultDescription, pipeline.TypeRouteCurrency,
+	}
+	tlsVersions    = []string{"1.2", "1.3"}
+	stageSinks     = []string{"hdfs", "discard"}
+	replayMappings = []string{"id", "name", "amount", "currency", "created_at", "description", "status"}
+)
+
+// Section is a part of the config a command uses.
+type Section uint
+
+const (
+	// SectionBroker is the broker connection and topic.
+	SectionBroker Section = 1 << iota
+	// SectionRegistry is the schema registry, serde format and topic.
+	SectionRegistry
+	// SectionHDFS is the HDFS connection.
+	SectionHDFS
+	// SectionValidation is the payment validation rules.
+	SectionValidation
+)
+
+// Validate checks every field of the run command and returns all
+// problems joined, each of them is a FieldError.
+func (c Config) Validate() error {
+	v := validator{}
+
+	for i, role := range c.Roles {
+		v.oneOf(fmt.Sprintf("roles[%d]", i), role, Roles)
+	}
+	producer, consumer := c.HasRole(RoleProducer), c.HasRole(RoleConsumer)
+
+	sections := SectionBroker | SectionRegistry | SectionValidation
+	// a producer reads HDFS only to replay a file from it
+	if consumer || (producer && strings.HasPrefix(c.Replay.Path, "hdfs://")) {
+		sections |= SectionHDFS
+	}
+	c.validateSections(&v, sections)
+
+	if producer && c.PaymentsGenTick <= 0 &&
+		c.PaymentsGenScenario == "" && c.Replay.Path == "" {
+		v.add("payments_gen_tick", "must be positive")
+	}
+	v.fileExists("payments_gen_scenario", c.PaymentsGenScenario)
+	if consumer {
+		v.notEmpty("broker.consumer_group", c.Broker.ConsumerGroup)
+	}
+	c.Aggregation.validate(&v)
+	c.Fraud.validate(&v)
+	c.validatePipeline(&v)
+	c.Replay.validate(&v)
+	c.Tuning.validate(&v)
+	c.Health.validate(&v)
+
+	return errors.Join(v.errs...)
+}
+
+// ValidateSections checks only the sections a command uses,
+// the other fields may be left invalid or empty.
+func (c Config) ValidateSections(sections Section) error {
+	v := validator{}
+	c.validateSections(&v, sections)
+	return errors.Join(v.errs...)
+}
+
+func (c Config) validateSections(v *validator, sections Section) {
+	broker := sections&SectionBroker != 0
+	registry := sections&SectionRegistry != 0
+	if broker || registry {
+		c.Broker.validate(v, broker, registry)
+	}
+	if sections&SectionHDFS != 0 {
+		v.notEmpty("hdfs.address", c.HDFS.Address)
+		v.notEmpty("hdfs.user", c.HDFS.User)
+	}
+	if sections&SectionValidation != 0 {
+		c.Validation.validate(v)
+	}
+}
+
+// validate checks the broker connection and the schema registry,
+// TLS files, credentials and the topic are shared by both.
+func (b brokerConfig) validate(v *validator, broker, registry bool) {
+	v.notEmpty("broker.topic", b.Topic)
+	v.fileExists("broker.ca_root_cert", b.CARootCert)
+	if (b.ClientCert == "") != (b.ClientKey == "") {
+		v.add("broker.client_key", "client_cert and client_key are set together")
+	}
+	v.fileExists("broker.client_cert", b.ClientCert)
+	v.fileExists("broker.client_key", b.ClientKey)
+	v.oneOf("broker.tls_min_version", b.TLSMinVersion, tlsVersions)
+
+	oauthSASL := strings.EqualFold(b.SASLMechanism, security.MechanismOAuthBearer)
+	passSASL := security.UsesSASL(b.SecurityProtocol) && !oauthSASL
+	if (broker && passSASL) || (registry && b.SchemaRegistryAuth == security.AuthBasic) {
+		v.notEmpty("broker.user", b.User)
+		if b.Pass == "" && b.PassFile == "" {
+			v.add("broker.pass", "is empty and broker.pass_file is not set")
+		}
+	}
+	v.fileExists("broker.pass_file", b.PassFile)
+
+	if (broker && security.UsesSASL(b.SecurityProtocol) && oauthSASL) ||
+		(registry && b.SchemaRegistryAuth == security.AuthBearer) {
+		b.OAuth.validate(v)
+	}
+
+	if broker {
+		if len(b.SeedBrokers) == 0 {
+			v.add("broker.seed_brokers", "is empty")
+		}
+		for i, addr := range b.SeedBrokers {
+			v.notEmpty(fmt.Sprintf("broker.seed_brokers[%d]", i), addr)
+		}
+		v.oneOf("broker.security_protocol", b.SecurityProtocol, security.Protocols)
+		v.oneOf("broker.sasl_mechanism", strings.ToUpper(b.SASLMechanism), security.Mechanisms)
+	}
+
+	if registry {
+		v.oneOf("broker.schema_registry_auth", b.SchemaRegistryAuth, security.Auths)
+		if len(b.SchemaRegistryURLs) == 0 {
+			v.add("broker.schema_registry_urls", "is empty")
+		}
+		for i, raw := range b.SchemaRegistryURLs {
+			u, err := url.Parse(raw)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				v.add(fmt.Sprintf("broker.schema_registry_urls[%d]", i), "is not an http(s) URL %q", raw)
+			}
+		}
+		if b.SchemaCompatibility != "" {
+			var l sr.CompatibilityLevel
+			if err := l.UnmarshalText([]byte(b.SchemaCompatibility)); err != nil {
+				v.add("broker.schema_compatibility", "unknown level %q", b.SchemaCompatibility)
+			}
+		}
+		v.oneOf("broker.serde_format", b.SerdeFormat, serdeFormats)
+		v.oneOf("broker.subject_name_strategy", b.SubjectNameStrategy, subjectNameStrategies)
+	}
+}
+
+func (o oauthConfig) validate(v *validator) {
+	u, err := url.Parse(o.TokenURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.add("broker.oauth.token_url", "is not an http(s) URL %q", o.TokenURL)
+	}
+	v.notEmpty("broker.oauth.client_id", o.ClientID)
+	if o.ClientSecret == "" && o.ClientSecretFile == "" {
+		v.add("broker.oauth.client_secret", "is empty and client_secret_file is not set")
+	}
+	v.fileExists("broker.oauth.client_secret_file", o.ClientSecretFile)
+	if o.RefreshBefore < 0 {
+		v.add("broker.oauth.refresh_before", "is negative")
+	}
+}
+
+func (c validationConfig) validate(v *validator) {
+	lo := v.decimal("validation.min_amount", c.MinAmount)
+	hi := v.decimal("validation.max_amount", c.MaxAmount)
+	if lo != nil && hi != nil && lo.Cmp(hi) > 0 {
+		v.add("validation.max_amount", "is less than min_amount")
+	}
+	for currency, l := range c.AmountLimits {
+		path := "validation.amount_limits." + currency
+		v.currencies(path, []string{strings.ToUpper(currency)})
+		lo := v.decimal(path+".min", l.Min)
+		hi := v.decimal(path+".max", l.Max)
+		if lo != nil && hi != nil && lo.Cmp(hi) > 0 {
+			v.add(path+".max", "is less than min")
+		}
+	}
+	v.currencies("validation.allowed_currencies", c.AllowedCurrencies)
+	if c.NamePattern != "" {
+		if _, err := regexp.Compile(c.NamePattern); err != nil {
+			v.add("validation.name_pattern", "%v", err)
+		}
+	}
+	if c.NameMaxLength < 0 {
+		v.add("validation.name_max_length", "is negative")
+	}
+}
+
+func (c aggregationConfig) validate(v *validator) {
+	if c.WindowSize == 0 {
+		return
+	}
+	if c.WindowSize < 0 {
+		v.add("aggregation.window_size", "is negative")
+	}
+	if c.WindowSlide < 0 || c.WindowSlide > c.WindowSize {
+		v.add("aggregation.window_slide", "must be between 0 and window_size")
+	}
+	if c.AllowedLateness < 0 {
+		v.add("aggregation.allowed_lateness", "is negative")
+	}
+	if c.MaxClockSkew < 0 {
+		v.add("aggregation.max_clock_skew", "is negative")
+	}
+	v.notEmpty("aggregation.output_topic", c.OutputTopic)
+}
+
+func (c fraudConfig) validate(v *validator) {
+	if c.AlertsTopic == "" {
+		return
+	}
+	if c.ZScoreK < 0 || c.ZScoreMinSamples < 0 || c.ZScoreSamples < 0 ||
+		c.VelocityLimit < 0 || c.VelocityWindow < 0 || c.DuplicateWindow < 0 || c.MaxPayers < 0 {
+		v.add("fraud", "parameters must not be negative")
+	}
+	if c.ZScoreK > 0 && (c.ZScoreMinSamples < 2 || c.ZScoreSamples < c.ZScoreMinSamples) {
+		v.add("fraud.zscore_samples", "needs 2 <= zscore_min_samples <= zscore_samples")
+	}
+	if c.VelocityLimit > 0 && c.VelocityWindow <= 0 {
+		v.add("fraud.velocity_window", "must be positive with velocity_limit")
+	}
+	if c.ZScoreK == 0 && c.VelocityLimit == 0 && c.DuplicateWindow == 0 {
+		v.add("fraud", "no rules are enabled")
+	}
+}
+
+// validatePipeline builds the stages with stand-in sinks when the
+// fields are valid, so names and statuses are checked like at start.
+func (c Config) validatePipeline(v *validator) {
+	n := len(v.errs)
+	specs := make([]pipeline.StageSpec, 0, len(c.Pipeline))
+	for i, st := range c.Pipeline {
+		st.validate(v, fmt.Sprintf("pipeline[%d]", i))
+		specs = append(specs, pipeline.StageSpec(st))
+	}
+	if len(v.errs) != n {
+		return
+	}
+
+	sinks := make(map[string]port.PaymentsStorage, len(stageSinks))
+	for _, name := range stageSinks {
+		sinks[name] = pipeline.Discard
+	}
+	if _, err := pipeline.Build(specs, sinks); err != nil {
+		var stageErr *pipeline.StageError
+		if errors.As(err, &stageErr) {
+			v.add(fmt.Sprintf("pipeline[%d]", stageErr.Index), "%v", stageErr.Err)
+			return
+		}
+		v.add("pipeline", "%v", err)
+	}
+}
+
+func (c stageConfig) validate(v *validator, path string) {
+	if c.Type == "" {
+		v.add(path+".type", "is empty")
+		return
+	}
+	v.oneOf(path+".type", c.Type, stageTypes)
+	v.currencies(path+".currencies", c.Currencies)
+	for currency, sink := range c.Routes {
+		v.currencies(path+".routes", []string{strings.ToUpper(currency)})
+		v.oneOf(path+".routes."+currency, sink, stageSinks)
+	}
+	if c.Keep < 0 {
+		v.add(path+".keep", "is negative")
+	}
+}
+
+func (c replayConfig) validate(v *validator) {
+	if c.Path == "" {
+		return
+	}
+	if !strings.HasPrefix(c.Path, "hdfs://") {
+		v.fileExists("replay.path", c.Path)
+	}
+	v.oneOf("replay.format", c.Format, replayfmt.Formats())
+	if c.Format == "" {
+		if _, err := replayfmt.Of(c.Path); err != nil {
+			v.add("replay.format", "is required, the replay.path extension is unknown")
+		}
+	}
+	if c.Speed < 0 {
+		v.add("replay.speed", "is negative")
+	}
+	if c.Progress <= 0 {
+		v.add("replay.progress", "must be positive")
+	}
+	for key := range c.Mapping {
+		if !slices.Contains(replayMappings, key) {
+			v.add("replay.mapping."+key, "unknown payment field")
+		}
+	}
+}
+
+func (c tuningConfig) validate(v *validator) {
+	if c.ConsumerBatchSize < 0 {
+		v.add("tuning.consumer_batch_size", "is negative")
+	}
+	if c.ConsumerRetryBackoff <= 0 {
+		v.add("tuning.consumer_retry_backoff", "must be positive")
+	}
+	if c.HDFSRetryBackoff <= 0 {
+		v.add("tuning.hdfs_retry_backoff", "must be positive")
+	}
+}
+
+func (c healthConfig) validate(v *validator) {
+	if c.Port < 0 || c.Port > 65535 {
+		v.add("health.port", "is not a port %d", c.Port)
+	}
+	if c.Port == 0 {
+		return
+	}
+	if c.CheckTimeout <= 0 {
+		v.add("health.check_timeout", "must be positive")
+	}
+	if c.MaxPollAge < 0 {
+		v.add("health.max_poll_age", "is negative")
+	}
+	if c.MaxSaveAge < 0 {
+		v.add("health.max_save_age", "is negative")
+	}
+}
+
+type validator struct {
+	errs []error
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) notEmpty(path, s string) {
+	if strings.TrimSpace(s) == "" {
+		v.add(path, "is empty")
+	}
+}
+
+// oneOf allows empty values, they are defaults.
+func (v *validator) oneOf(path, s string, allowed []string) {
+	if s != "" && !slices.Contains(allowed, s) {
+		v.add(path, "is %q, want one of %s", s, strings.Join(allowed, "|"))
+	}
+}
+
+func (v *validator) fileExists(path, file string) {
+	if file == "" {
+		return
+	}
+	if _, err := os.Stat(file); err != nil {
+		v.add(path, "%v", err)
+	}
+}
+
+func (v *validator) decimal(path, s string) *big.Rat {
+	if s == "" {
+		return nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		v.add(path, "is not a decimal %q", s)
+		return nil
+	}
+	return r
+}
+
+func (v *validator) currencies(path string, cs []string) {
+	for _, c := range cs {
+		if !domain.Currency(c).Valid() {
+			v.add(path, "unsupported currency %q", c)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+//go:build !integration
+
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig(t *testing.T) Config {
+	t.Helper()
+	ca := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(ca, []byte("pem"), 0o600))
+	return Config{
+		PaymentsGenTick: time.Second,
+		Broker: brokerConfig{
+			SeedBrokers:        []string{"broker:9091"},
+			Topic:              "payments",
+			ConsumerGroup:      "group",
+			CARootCert:         ca,
+			User:               "user",
+			Pass:               "pass",
+			SchemaRegistryURLs: []string{"https://sr:443"},
+			SerdeFormat:        "avro",
+		},
+		HDFS: hdfsConfig{Address: "hdfs:9000", User: "hdfs"},
+		Tuning: tuningConfig{
+			ConsumerRetryBackoff: time.Second, HDFSRetryBackoff: time.Second,
+		},
+	}
+}
+
+func TestValidateOK(t *testing.T) {
+	assert.NoError(t, validConfig(t).Validate())
+}
+
+func TestValidateReportsAllFields(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.PaymentsGenTick = 0
+	cfg.Broker.Topic = ""
+	cfg.Broker.CARootCert = "/missing/ca.pem"
+	cfg.Broker.SchemaRegistryURLs = []string{"sr:443"}
+	cfg.Broker.SerdeFormat = "xml"
+	cfg.Validation.MinAmount = "10"
+	cfg.Validation.MaxAmount = "1"
+	cfg.Validation.AmountLimits = map[string]amountLimitsConfig{
+		"zzz": {Min: "1"},
+		"jpy": {Min: "10", Max: "1"},
+	}
+	cfg.Aggregation.WindowSize = time.Minute
+	cfg.Pipeline = []stageConfig{{Type: "route_currency", Routes: map[string]string{"USD": "s3"}}}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+
+	var paths []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe FieldError
+		require.True(t, errors.As(e, &fe))
+		paths = append(paths, fe.Path)
+	}
+	assert.ElementsMatch(t, []string{
+		"payments_gen_tick",
+		"broker.topic",
+		"broker.ca_root_cert",
+		"broker.schema_registry_urls[0]",
+		"broker.serde_format",
+		"validation.max_amount",
+		"validation.amount_limits.zzz",
+		"validation.amount_limits.jpy.max",
+		"aggregation.output_topic",
+		"pipeline[0].routes.USD",
+	}, paths)
+}
+
+func TestValidateOAuth(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Broker.SecurityProtocol = "sasl_ssl"
+	cfg.Broker.SASLMechanism = "OAUTHBEARER"
+	cfg.Broker.SchemaRegistryAuth = "bearer"
+	cfg.Broker.User, cfg.Broker.Pass = "", ""
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "broker.pass")
+	assert.Contains(t, err.Error(), "broker.oauth.token_url")
+	assert.Contains(t, err.Error(), "broker.oauth.client_id")
+
+	cfg.Broker.OAuth = oauthConfig{
+		TokenURL: "https://idp/token", ClientID: "client", ClientSecret: "secret",
+	}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateRoles(t *testing.T) {
+	cfg := validConfig(t)
+	cfg.Roles = []string{RoleConsumer}
+	cfg.PaymentsGenTick = 0
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Roles = []string{RoleProducer}
+	cfg.PaymentsGenTick = time.Second
+	cfg.Broker.ConsumerGroup = ""
+	cfg.HDFS = hdfsConfig{}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Replay.Path = "hdfs:///payments.csv"
+	cfg.Replay.Progress = time.Second
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hdfs.address")
+
+	cfg.Roles = []string{"router"}
+	assert.ErrorContains(t, cfg.Validate(), "roles[0]")
+}
+
+func TestValidatePipelineLikeStart(t *testing.T) {
+	for _, stages := range [][]stageConfig{
+		{{Type: "mask_name"}, {Type: "mask_name", Keep: 1}},
+		{{Type: "filter_status", Statuses: []string{"lost"}}},
+		{{Name: "store", Type: "mask_name"}},
+	} {
+		cfg := validConfig(t)
+		cfg.Pipeline = stages
+
+		err := cfg.Validate()
+		require.Error(t, err, "%+v", stages)
+		var fe FieldError
+		require.True(t, errors.As(err, &fe))
+		assert.Contains(t, fe.Path, "pipeline[")
+	}
+
+	cfg := validConfig(t)
+	cfg.Pipeline = []stageConfig{
+		{Type: "mask_name"}, {Name: "mask_again", Type: "mask_name", Keep: 1},
+	}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateSections(t *testing.T) {
+	cfg := Config{HDFS: hdfsConfig{Address: "hdfs:9000", User: "hdfs"}}
+	assert.NoError(t, cfg.ValidateSections(SectionHDFS))
+	assert.Error(t, cfg.Validate())
+
+	cfg = Config{Broker: brokerConfig{
+		Topic: "payments", SchemaRegistryURLs: []string{"http://localhost:8081"},
+		SchemaRegistryAuth: "none",
+	}}
+	assert.NoError(t, cfg.ValidateSections(SectionRegistry))
+
+	err := cfg.ValidateSections(SectionBroker | SectionRegistry)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broker.seed_brokers")
+	assert.NotContains(t, err.Error(), "hdfs.address")
+}
@@ -1,9 +1,11 @@
 package adapter
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/colinmarc/hdfs/v2"
@@ -13,6 +15,8 @@ import (
 )
 
 var _ port.PaymentsStorage = (*HDFSStorage)(nil)
+var _ port.RejectedPaymentsStorage = (*HDFSStorage)(nil)
+var _ port.AggregatesSink = (*HDFSStorage)(nil)
 
 type HDFSOption func(*hdfsStorageOpts) error
 
@@ -26,12 +30,29 @@ func HDFSClientOpt(cl *hdfs.Client) HDFSOption {
 	}
 }
 
+// HDFSRetryBackoffOpt is the pause between closes
+// of a file that is still replicating.
+func HDFSRetryBackoffOpt(d time.Duration) HDFSOption {
+	return func(hso *hdfsStorageOpts) error {
+		if d <= 0 {
+			return errors.New("hdfs retry backoff is not positive")
+		}
+		hso.retryBackoff = d
+		return nil
+	}
+}
+
 type hdfsStorageOpts struct {
-	cl *hdfs.Client
+	cl           *hdfs.Client
+	retryBackoff time.Duration
 }
 
 type HDFSStorage struct {
 	cl *hdfs.Client
+	// retryBackoff is shared by the copies of the storage
+	retryBackoff *atomic.Int64
+	// lastSave is unix nanoseconds of the last written file
+	lastSave *atomic.Int64
 }
 
 func NewHDFStorage(opts ...HDFSOption) HDFSStorage {
@@ -41,13 +62,36 @@ func NewHDFStorage(opts ...HDFSOption) HDFSStorage {
 		panic(fmt.Errorf("%s: options not set", op))
 	}
 
-	var options hdfsStorageOpts
+	options := hdfsStorageOpts{retryBackoff: 1 * time.Second}
 	for _, opt := range opts {
 		if err := opt(&options); err != nil {
 			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
 		}
 	}
-	return HDFSStorage{options.cl}
+	s := HDFSStorage{
+		cl:           options.cl,
+		retryBackoff: new(atomic.Int64),
+		lastSave:     new(atomic.Int64),
+	}
+	s.SetRetryBackoff(options.retryBackoff)
+	return s
+}
+
+// SetRetryBackoff applies to the next retry.
+func (s HDFSStorage) SetRetryBackoff(d time.Duration) {
+	if d > 0 {
+		s.retryBackoff.Store(int64(d))
+	}
+}
+
+// LastSave is the time of the last written file,
+// zero before the first one.
+func (s HDFSStorage) LastSave() time.Time {
+	ns := s.lastSave.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
 }
 
 func (s HDFSStorage) Close(onFall func(error)) {
@@ -56,18 +100,74 @@ func (s HDFSStorage) Close(onFall func(error)) {
 	}
 }
 
-func (s HDFSStorage) Save(ps []domain.Payment) error {
+func (s HDFSStorage) Save(es []domain.PaymentEnvelope) error {
 	const op = "HDFSStorage.Save"
 	log := slog.With("op", op)
 
-	filename := s.createFilepath()
+	filename := s.createFilepath("/payments_")
+	records := make([]any, 0, len(es))
+	for _, e := range es {
+		records = append(records, toPaymentRecord(e))
+	}
+
+	if err := s.writeLines(filename, records); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("payments data saved successfully", "filename", filename)
+	return nil
+}
+
+func (s HDFSStorage) SaveRejected(rs []domain.RejectedPayment) error {
+	const op = "HDFSStorage.SaveRejected"
+	log := slog.With("op", op)
+
+	filename := s.createFilepath("/rejected_payments_")
+	records := make([]any, 0, len(rs))
+	for _, r := range rs {
+		records = append(records, toRejectedRecord(r))
+	}
+
+	if err := s.writeLines(filename, records); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
 
+	log.Info("rejected payments saved successfully", "filename", filename)
+	return nil
+}
+
+func (s HDFSStorage) SaveAggregates(ws []domain.WindowAggregate) error {
+	const op = "HDFSStorage.SaveAggregates"
+	log := slog.With("op", op)
+
+	filename := s.createFilepath("/aggregates_")
+	records := make([]any, 0, len(ws))
+	for _, w := range ws {
+		records = append(records, toAggregateRecord(w))
+	}
+
+	if err := s.writeLines(filename, records); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("aggregates saved successfully", "filename", filename)
+	return nil
+}
+
+// writeLines writes records as JSON lines to a new file.
+func (s HDFSStorage) writeLines(filename string, records []any) error {
 	fw, err := s.cl.Create(filename)
 	if err != nil {
-		return fmt.Errorf("%s: failed to create file: %w", op, err)
+		return fmt.Errorf("failed to create file: %w", err)
 	}
 
-	fmt.Fprintf(fw, "%+v\n", ps)
+	enc := json.NewEncoder(fw)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			fw.Close()
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
 
 	timer := time.NewTimer(0)
 	defer timer.Stop()
@@ -77,18 +177,103 @@ func (s HDFSStorage) Save(ps []domain.Payment) error {
 		err = fw.Close()
 		if err != nil {
 			if errors.Is(err, hdfs.ErrReplicating) {
-				timer.Reset(1 * time.Second)
+				timer.Reset(time.Duration(s.retryBackoff.Load()))
 				continue
 			}
-			return fmt.Errorf("%s: failed to close file: %w", op, err)
+			return fmt.Errorf("failed to close file: %w", err)
 		}
-		break
+		s.lastSave.Store(time.Now().UnixNano())
+		return nil
 	}
+}
 
-	log.Info("payments data saved successfully", "filename", filename)
-	return nil
+func (s HDFSStorage) createFilepath(prefix string) string {
+	return prefix + uuid.NewString()
+}
+
+// paymentRecord is a JSON line of the payments file. The amount is
+// stored both as a decimal string and in minor units to avoid floats.
+type paymentRecord struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	Amount        string    `json:"amount"`
+	AmountMinor   int64     `json:"amount_minor"`
+	Currency      string    `json:"currency"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	Description   string    `json:"description,omitempty"`
+	ProducerID    string    `json:"producer_id,omitempty"`
+	SchemaSubject string    `json:"schema_subject,omitempty"`
+	SchemaVersion int       `json:"schema_version,omitempty"`
+	ContentType   string    `json:"content_type,omitempty"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	EventType     string    `json:"event_type,omitempty"`
 }
 
-func (s HDFSStorage) createFilepath() string {
-	return "/payments_" + uuid.NewString()
+func toPaymentRecord(e domain.PaymentEnvelope) paymentRecord {
+	p, md := e.Payment, e.Metadata
+	return paymentRecord{
+		ID:            p.ID,
+		Name:          p.Name,
+		Amount:        p.Amount.Decimal(),
+		AmountMinor:   p.Amount.Minor,
+		Currency:      string(p.Amount.Currency),
+		Status:        string(p.Status),
+		CreatedAt:     p.CreatedAt,
+		Description:   p.Description,
+		ProducerID:    md.ProducerID,
+		SchemaSubject: md.SchemaSubject,
+		SchemaVersion: md.SchemaVersion,
+		ContentType:   md.ContentType,
+		CorrelationID: md.CorrelationID,
+		EventType:     string(md.EventType),
+	}
+}
+
+type rejectionRecord struct {
+	Rule    string `json:"rule"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type rejectedRecord struct {
+	paymentRecord
+	Reasons    []rejectionRecord `json:"reasons"`
+	RejectedAt time.Time         `json:"rejected_at"`
+}
+
+func toRejectedRecord(r domain.RejectedPayment) rejectedRecord {
+	reasons := make([]rejectionRecord, 0, len(r.Reasons))
+	for _, rr := range r.Reasons {
+		reasons = append(reasons, rejectionRecord(rr))
+	}
+	return rejectedRecord{
+		paymentRecord: toPaymentRecord(r.Envelope),
+		Reasons:       reasons,
+		RejectedAt:    r.RejectedAt,
+	}
+}
+
+type aggregateRecord struct {
+	Payer       string    `json:"payer"`
+	Currency    string    `json:"currency"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Count       int64     `json:"count"`
+	Sum         string    `json:"sum"`
+	Min         string    `json:"min"`
+	Max         string    `json:"max"`
+}
+
+func toAggregateRecord(w domain.WindowAggregate) aggregateRecord {
+	return aggregateRecord{
+		Payer:       w.Payer,
+		Currency:    string(w.Currency),
+		WindowStart: w.Start,
+		WindowEnd:   w.End,
+		Count:       w.Count,
+		Sum:         w.Sum.Decimal(),
+		Min:         w.Min.Decimal(),
+		Max:         w.Max.Decimal(),
+	}
 }
@@ -0,0 +1,129 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/colinmarc/hdfs/v2"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+// MetadataClient is implemented by *kgo.Client.
+type MetadataClient interface {
+	Request(ctx context.Context, req kmsg.Request) (kmsg.Response, error)
+}
+
+// GroupClient is implemented by *kgo.Client.
+type GroupClient interface {
+	GroupMetadata() (memberID string, generation int32)
+}
+
+// RegistryClient is implemented by *sr.Client.
+type RegistryClient interface {
+	SupportedTypes(ctx context.Context) ([]sr.SchemaType, error)
+}
+
+// FsClient is implemented by *hdfs.Client.
+type FsClient interface {
+	StatFs() (hdfs.FsInfo, error)
+}
+
+// BrokerMetadata requests the metadata of the topic, it fails when
+// no broker answers or the topic has an error, e.g. it is missing.
+func BrokerMetadata(cl MetadataClient, topic string) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		req := kmsg.NewPtrMetadataRequest()
+		reqTopic := kmsg.NewMetadataRequestTopic()
+		reqTopic.Topic = kmsg.StringPtr(topic)
+		req.Topics = append(req.Topics, reqTopic)
+
+		resp, err := cl.Request(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		md, ok := resp.(*kmsg.MetadataResponse)
+		if !ok {
+			return nil, fmt.Errorf("unexpected metadata response %T", resp)
+		}
+
+		details := map[string]any{
+			"brokers":    len(md.Brokers),
+			"controller": md.ControllerID,
+		}
+		for _, t := range md.Topics {
+			if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+				return details, fmt.Errorf("topic %q: %w", topic, err)
+			}
+			details["partitions"] = len(t.Partitions)
+		}
+		return details, nil
+	}
+}
+
+// SchemaRegistry requests the supported schema types.
+func SchemaRegistry(cl RegistryClient) CheckFunc {
+	return func(ctx context.Context) (any, error) {
+		types, err := cl.SupportedTypes(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"schema_types": types}, nil
+	}
+}
+
+// HDFS reports the filesystem usage.
+func HDFS(cl FsClient) CheckFunc {
+	return func(context.Context) (any, error) {
+		fs, err := cl.StatFs()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{
+			"capacity":  fs.Capacity,
+			"used":      fs.Used,
+			"remaining": fs.Remaining,
+		}, nil
+	}
+}
+
+var ErrNotMember = errors.New("not a member of the consumer group")
+
+// GroupMember fails until the client joins its consumer group.
+func GroupMember(cl GroupClient) CheckFunc {
+	return func(context.Context) (any, error) {
+		memberID, generation := cl.GroupMetadata()
+		details := map[string]any{"member_id": memberID, "generation": generation}
+		if memberID == "" || generation < 0 {
+			return details, ErrNotMember
+		}
+		return details, nil
+	}
+}
+
+var ErrTooOld = errors.New("last success is too old")
+
+// Age fails when the last success is older than maxAge, zero maxAge
+// only reports the age. Before the first success the age is counted
+// from the creation of the check.
+func Age(last func() time.Time, maxAge time.Duration) CheckFunc {
+	created := time.Now()
+	return func(context.Context) (any, error) {
+		t := last()
+		details := map[string]any{"last": nil}
+		if t.IsZero() {
+			t = created
+		} else {
+			details["last"] = t
+		}
+		age := time.Since(t)
+		details["age"] = age.Round(time.Millisecond).String()
+		if maxAge > 0 && age > maxAge {
+			return details, fmt.Errorf("%w: %s > %s", ErrTooOld, details["age"], maxAge)
+		}
+		return details, nil
+	}
+}
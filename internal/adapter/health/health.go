@@ -0,0 +1,321 @@
+// Package health serves the liveness and readiness endpoints
+// and checks the dependencies of the application.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+var metrics = expvar.NewMap("health_checks")
+
+// Statuses of a check and of the whole report.
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+const (
+	defaultAddr    = ":8080"
+	defaultTimeout = 5 * time.Second
+)
+
+// CheckFunc checks a dependency, details are reported
+// whether the check passes or not.
+type CheckFunc func(ctx context.Context) (details any, err error)
+
+// CheckResult is the JSON status of a check.
+type CheckResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms"`
+	Details    any     `json:"details,omitempty"`
+}
+
+// Report is the body of /healthz and /readyz.
+type Report struct {
+	Status string                 `json:"status"`
+	Uptime string                 `json:"uptime"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+type Opt func(*options) error
+
+// AddrOpt is the listen address, :8080 by default.
+func AddrOpt(addr string) Opt {
+	return func(o *options) error {
+		if addr == "" {
+			return errors.New("health address is empty")
+		}
+		o.addr = addr
+		return nil
+	}
+}
+
+// CheckOpt adds a readiness check, the names are unique.
+func CheckOpt(name string, fn CheckFunc) Opt {
+	return func(o *options) error {
+		if name == "" || fn == nil {
+			return errors.New("health check name is empty or func is nil")
+		}
+		if slices.ContainsFunc(o.checks, func(c *check) bool { return c.name == name }) {
+			return fmt.Errorf("health check %q is added twice", name)
+		}
+		o.checks = append(o.checks, &check{name: name, fn: fn})
+		return nil
+	}
+}
+
+// TimeoutOpt limits every check, a check that does not
+// return in time fails.
+func TimeoutOpt(d time.Duration) Opt {
+	return func(o *options) error {
+		if d <= 0 {
+			return errors.New("health check timeout is not positive")
+		}
+		o.timeout = d
+		return nil
+	}
+}
+
+type options struct {
+	addr    string
+	checks  []*check
+	timeout time.Duration
+}
+
+// check runs once at a time, concurrent probes share the running call,
+// so a check that ignores its context does not pile up goroutines.
+type check struct {
+	name string
+	fn   CheckFunc
+
+	mu       sync.Mutex
+	inflight *call
+}
+
+type call struct {
+	done    chan struct{}
+	details any
+	err     error
+}
+
+// Server answers /healthz while the process runs and /readyz with
+// the checks, the latter is 503 when any check fails.
+type Server struct {
+	addr    string
+	checks  []*check
+	timeout time.Duration
+	started time.Time
+
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+func NewServer(opts ...Opt) *Server {
+	const op = "health.NewServer"
+
+	options := options{addr: defaultAddr, timeout: defaultTimeout}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
+		}
+	}
+	return &Server{
+		addr:    options.addr,
+		checks:  options.checks,
+		timeout: options.timeout,
+		started: time.Now(),
+		failed:  make(map[string]bool),
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, Report{Status: StatusOK, Uptime: s.uptime()})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, s.Ready(r.Context()))
+	})
+	mux.HandleFunc("GET /debug/vars", serveVars)
+	return mux
+}
+
+// debugVars are the counters of the pipeline, validation, aggregation,
+// fraud rules and checks. The standard cmdline and memstats vars are
+// not served, the command line may hold the broker secrets.
+var debugVars = []string{
+	"payment_pipeline",
+	"payment_validation",
+	"payment_aggregation",
+	"fraud_detection",
+	"health_checks",
+}
+
+func serveVars(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	fmt.Fprint(w, "{")
+	sep := ""
+	for _, name := range debugVars {
+		// a var is missing when its package is not linked
+		v := expvar.Get(name)
+		if v == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\n%q: %s", sep, name, v)
+		sep = ","
+	}
+	fmt.Fprint(w, "\n}\n")
+}
+
+// Run serves until the context is done.
+func (s *Server) Run(ctx context.Context) error {
+	const op = "health.Server.Run"
+
+	srv := &http.Server{
+		Addr:              s.addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shutdown health server", "op", op, "err", err)
+		}
+	}()
+
+	slog.Info("health server is started", "op", op, "addr", s.addr)
+	err := srv.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+// Ready runs the checks concurrently.
+func (s *Server) Ready(ctx context.Context) Report {
+	results := make([]CheckResult, len(s.checks))
+	var wg sync.WaitGroup
+	for i, c := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = s.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{
+		Status: StatusOK,
+		Uptime: s.uptime(),
+		Checks: make(map[string]CheckResult, len(s.checks)),
+	}
+	for i, c := range s.checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusOK {
+			report.Status = StatusFail
+		}
+		s.logChange(c.name, results[i])
+	}
+	return report
+}
+
+// run gives up on checks that ignore the context, like HDFS calls,
+// such a check keeps failing until its call returns.
+func (s *Server) run(ctx context.Context, c *check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	cl := s.call(c)
+
+	var (
+		details any
+		err     error
+	)
+	select {
+	case <-cl.done:
+		details, err = cl.details, cl.err
+	case <-ctx.Done():
+		err = fmt.Errorf("check is not completed: %w", ctx.Err())
+	}
+
+	cr := CheckResult{
+		Status:     StatusOK,
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+		Details:    details,
+	}
+	if err != nil {
+		cr.Status = StatusFail
+		cr.Error = err.Error()
+		metrics.Add(c.name+"_failures", 1)
+	}
+	return cr
+}
+
+// call returns the running call of the check or starts a new one,
+// the call is not bound to a probe that may go away.
+func (s *Server) call(c *check) *call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight != nil {
+		return c.inflight
+	}
+
+	cl := &call{done: make(chan struct{})}
+	c.inflight = cl
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		cl.details, cl.err = c.fn(ctx)
+
+		c.mu.Lock()
+		c.inflight = nil
+		c.mu.Unlock()
+		close(cl.done)
+	}()
+	return cl
+}
+
+// logChange logs the checks that start or stop failing,
+// probes are too frequent to log every result.
+func (s *Server) logChange(name string, cr CheckResult) {
+	const op = "health.Server.Ready"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failed := cr.Status != StatusOK
+	if s.failed[name] == failed {
+		return
+	}
+	s.failed[name] = failed
+	if failed {
+		slog.Warn("health check fails", "op", op, "check", name, "err", cr.Error)
+		return
+	}
+	slog.Info("health check is recovered", "op", op, "check", name)
+}
+
+func (s *Server) uptime() string {
+	return time.Since(s.started).Round(time.Second).String()
+}
+
+func writeReport(w http.ResponseWriter, r Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Status != StatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(r)
+}
@@ -0,0 +1,152 @@
+//go:build !integration
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/colinmarc/hdfs/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+type stubGroup struct {
+	memberID   string
+	generation int32
+}
+
+func (g stubGroup) GroupMetadata() (string, int32) { return g.memberID, g.generation }
+
+type stubFs struct{ err error }
+
+func (f stubFs) StatFs() (hdfs.FsInfo, error) {
+	return hdfs.FsInfo{Capacity: 100, Used: 40, Remaining: 60}, f.err
+}
+
+type stubMetadata struct{ topicErr int16 }
+
+func (m stubMetadata) Request(context.Context, kmsg.Request) (kmsg.Response, error) {
+	resp := kmsg.NewPtrMetadataResponse()
+	resp.Brokers = make([]kmsg.MetadataResponseBroker, 3)
+	topic := kmsg.NewMetadataResponseTopic()
+	topic.ErrorCode = m.topicErr
+	topic.Partitions = make([]kmsg.MetadataResponseTopicPartition, 6)
+	resp.Topics = append(resp.Topics, topic)
+	return resp, nil
+}
+
+func TestBrokerMetadata(t *testing.T) {
+	details, err := BrokerMetadata(stubMetadata{}, "payments")(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, details.(map[string]any)["brokers"])
+	assert.Equal(t, 6, details.(map[string]any)["partitions"])
+
+	_, err = BrokerMetadata(
+		stubMetadata{kerr.UnknownTopicOrPartition.Code}, "payments",
+	)(context.Background())
+	assert.ErrorIs(t, err, kerr.UnknownTopicOrPartition)
+}
+
+func get(t *testing.T, s *Server, path string) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var r Report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &r))
+	return rec.Code, r
+}
+
+func TestReady(t *testing.T) {
+	s := NewServer(
+		TimeoutOpt(50*time.Millisecond),
+		CheckOpt("hdfs", HDFS(stubFs{})),
+		CheckOpt("consumer_group", GroupMember(stubGroup{"member-1", 3})),
+		CheckOpt("last_poll", Age(time.Now, time.Minute)),
+	)
+	code, r := get(t, s, "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, r.Status)
+	require.Len(t, r.Checks, 3)
+	assert.Equal(t, float64(60), r.Checks["hdfs"].Details.(map[string]any)["remaining"])
+}
+
+func TestNotReady(t *testing.T) {
+	stale := func() time.Time { return time.Now().Add(-time.Hour) }
+	hang := func(ctx context.Context) (any, error) {
+		time.Sleep(time.Second)
+		return nil, nil
+	}
+	s := NewServer(
+		TimeoutOpt(50*time.Millisecond),
+		CheckOpt("hdfs", HDFS(stubFs{errors.New("namenode is down")})),
+		CheckOpt("consumer_group", GroupMember(stubGroup{"", -1})),
+		CheckOpt("last_poll", Age(stale, time.Minute)),
+		CheckOpt("last_save", Age(stale, 0)),
+		CheckOpt("hang", hang),
+	)
+	code, r := get(t, s, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusFail, r.Status)
+	assert.Equal(t, "namenode is down", r.Checks["hdfs"].Error)
+	assert.Equal(t, ErrNotMember.Error(), r.Checks["consumer_group"].Error)
+	assert.Contains(t, r.Checks["last_poll"].Error, ErrTooOld.Error())
+	assert.Equal(t, StatusOK, r.Checks["last_save"].Status)
+	assert.Contains(t, r.Checks["hang"].Error, "deadline exceeded")
+
+	// liveness does not run the checks
+	code, r = get(t, s, "/healthz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, r.Checks)
+}
+
+func TestDuplicateCheck(t *testing.T) {
+	check := HDFS(stubFs{})
+	assert.Panics(t, func() { NewServer(CheckOpt("hdfs", check), CheckOpt("hdfs", check)) })
+}
+
+func TestDebugVars(t *testing.T) {
+	s := NewServer()
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	assert.Contains(t, vars, "health_checks")
+	assert.NotContains(t, vars, "cmdline")
+	assert.NotContains(t, vars, "memstats")
+}
+
+func TestHangingCheckRunsOnce(t *testing.T) {
+	var calls atomic.Int64
+	release := make(chan struct{})
+	hang := func(ctx context.Context) (any, error) {
+		calls.Add(1)
+		<-release // ignores the context
+		return nil, nil
+	}
+	s := NewServer(TimeoutOpt(20*time.Millisecond), CheckOpt("hang", hang))
+
+	for range 5 {
+		code, r := get(t, s, "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Contains(t, r.Checks["hang"].Error, "deadline exceeded")
+	}
+	assert.EqualValues(t, 1, calls.Load(), "probes wait for the running call")
+
+	close(release)
+	require.Eventually(t, func() bool {
+		code, _ := get(t, s, "/readyz")
+		return code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+	assert.LessOrEqual(t, calls.Load(), int64(2))
+}
@@ -0,0 +1,101 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.AggregatesSink = (*AggregatesProducer)(nil)
+
+type AggregatesProducerOpt func(*aggregatesProducerOpts) error
+
+func AggregatesProducerClientOpt(cl ProducerClient) AggregatesProducerOpt {
+	return func(opts *aggregatesProducerOpts) error {
+		if cl != nil {
+			opts.cl = cl
+			return nil
+		}
+		return errors.New("aggregates producer client is nil")
+	}
+}
+
+func AggregatesProducerTopicOpt(topic string) AggregatesProducerOpt {
+	return func(opts *aggregatesProducerOpts) error {
+		if topic != "" {
+			opts.topic = topic
+			return nil
+		}
+		return errors.New("aggregates producer topic is empty")
+	}
+}
+
+type aggregatesProducerOpts struct {
+	cl    ProducerClient
+	topic string
+}
+
+// AggregatesProducer writes closed windows to the output topic as JSON,
+// records are keyed by payer and currency. It does not own the client.
+type AggregatesProducer struct {
+	cl    ProducerClient
+	topic string
+}
+
+func NewAggregatesProducer(opts ...AggregatesProducerOpt) AggregatesProducer {
+	const op = "NewAggregatesProducer"
+
+	var options aggregatesProducerOpts
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
+		}
+	}
+	if options.cl == nil || options.topic == "" {
+		panic(fmt.Errorf("%s: client or topic not set", op))
+	}
+	return AggregatesProducer{options.cl, options.topic}
+}
+
+func (p AggregatesProducer) SaveAggregates(ws []domain.WindowAggregate) error {
+	const op = "AggregatesProducer.SaveAggregates"
+
+	rs := make([]jsonRecord, 0, len(ws))
+	for _, w := range ws {
+		rs = append(rs, jsonRecord{
+			key:   w.Payer + "/" + string(w.Currency),
+			value: toAggregateValue(w),
+		})
+	}
+	if err := produceJSON(p.cl, p.topic, rs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+type aggregateValue struct {
+	Payer       string    `json:"payer"`
+	Currency    string    `json:"currency"`
+	WindowStart time.Time `json:"window_start"`
+	WindowEnd   time.Time `json:"window_end"`
+	Count       int64     `json:"count"`
+	Sum         string    `json:"sum"`
+	Min         string    `json:"min"`
+	Max         string    `json:"max"`
+}
+
+func toAggregateValue(w domain.WindowAggregate) aggregateValue {
+	return aggregateValue{
+		Payer:       w.Payer,
+		Currency:    string(w.Currency),
+		WindowStart: w.Start,
+		WindowEnd:   w.End,
+		Count:       w.Count,
+		Sum:         w.Sum.Decimal(),
+		Min:         w.Min.Decimal(),
+		Max:         w.Max.Decimal(),
+	}
+}
@@ -0,0 +1,98 @@
+package kafka
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.AlertsSink = (*AlertsProducer)(nil)
+
+type AlertsProducerOpt func(*alertsProducerOpts) error
+
+func AlertsProducerClientOpt(cl ProducerClient) AlertsProducerOpt {
+	return func(opts *alertsProducerOpts) error {
+		if cl != nil {
+			opts.cl = cl
+			return nil
+		}
+		return errors.New("alerts producer client is nil")
+	}
+}
+
+func AlertsProducerTopicOpt(topic string) AlertsProducerOpt {
+	return func(opts *alertsProducerOpts) error {
+		if topic != "" {
+			opts.topic = topic
+			return nil
+		}
+		return errors.New("alerts producer topic is empty")
+	}
+}
+
+type alertsProducerOpts struct {
+	cl    ProducerClient
+	topic string
+}
+
+// AlertsProducer writes fraud alerts to the alerts topic as JSON,
+// records are keyed by payment ID. It does not own the client.
+type AlertsProducer struct {
+	cl    ProducerClient
+	topic string
+}
+
+func NewAlertsProducer(opts ...AlertsProducerOpt) AlertsProducer {
+	const op = "NewAlertsProducer"
+
+	var options alertsProducerOpts
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
+		}
+	}
+	if options.cl == nil || options.topic == "" {
+		panic(fmt.Errorf("%s: client or topic not set", op))
+	}
+	return AlertsProducer{options.cl, options.topic}
+}
+
+func (p AlertsProducer) PublishAlerts(as []domain.FraudAlert) error {
+	const op = "AlertsProducer.PublishAlerts"
+
+	rs := make([]jsonRecord, 0, len(as))
+	for _, a := range as {
+		rs = append(rs, jsonRecord{key: a.PaymentID, value: toAlertValue(a)})
+	}
+	if err := produceJSON(p.cl, p.topic, rs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	return nil
+}
+
+type alertValue struct {
+	ID         string            `json:"id"`
+	Rule       string            `json:"rule"`
+	PaymentID  string            `json:"payment_id"`
+	Payer      string            `json:"payer"`
+	Amount     string            `json:"amount"`
+	Currency   string            `json:"currency"`
+	DetectedAt time.Time         `json:"detected_at"`
+	Evidence   map[string]string `json:"evidence"`
+}
+
+func toAlertValue(a domain.FraudAlert) alertValue {
+	return alertValue{
+		ID:         a.ID,
+		Rule:       a.Rule,
+		PaymentID:  a.PaymentID,
+		Payer:      a.Payer,
+		Amount:     a.Amount.Decimal(),
+		Currency:   string(a.Amount.Currency),
+		DetectedAt: a.DetectedAt,
+		Evidence:   a.Evidence,
+	}
+}
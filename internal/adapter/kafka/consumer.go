@@ -6,16 +6,22 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/niksmo/cloud-integration/internal/core/domain"
 	"github.com/niksmo/cloud-integration/internal/core/port"
 	"github.com/niksmo/cloud-integration/pkg/schema"
 	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sr"
 )
 
+// pollIdleTimeout ends polls of an idle topic, so the time of the
+// last successful poll tells that the consumer is alive.
+const pollIdleTimeout = 5 * time.Second
+
 type ConsumerClient interface {
-	PollFetches(context.Context) kgo.Fetches
+	PollRecords(ctx context.Context, maxPollRecords int) kgo.Fetches
 	CommitUncommittedOffsets(context.Context) error
 	Close()
 }
@@ -42,7 +48,9 @@ func ConsumerReceiverOpt(r port.PaymentReceiver) ConsumerOpt {
 	}
 }
 
-func ConsumerDecodeFnOpt(decodeFn func([]byte, any) error) ConsumerOpt {
+// ConsumerDecodeFnOpt sets the function that decodes a record value
+// into a new value of the type registered for the value schema.
+func ConsumerDecodeFnOpt(decodeFn func([]byte) (any, error)) ConsumerOpt {
 	return func(opts *consumerOpts) error {
 		if decodeFn != nil {
 			opts.decodeFn = decodeFn
@@ -52,17 +60,63 @@ func ConsumerDecodeFnOpt(decodeFn func([]byte, any) error) ConsumerOpt {
 	}
 }
 
+// ConsumerBatchSizeOpt limits records of a poll, every batch is
+// saved as one file, zero takes all fetched records.
+func ConsumerBatchSizeOpt(n int) ConsumerOpt {
+	return func(opts *consumerOpts) error {
+		if n < 0 {
+			return errors.New("consumer batch size is negative")
+		}
+		opts.batchSize = n
+		return nil
+	}
+}
+
+// ConsumerRetryBackoffOpt is the pause after a failed poll.
+func ConsumerRetryBackoffOpt(d time.Duration) ConsumerOpt {
+	return func(opts *consumerOpts) error {
+		if d <= 0 {
+			return errors.New("consumer retry backoff is not positive")
+		}
+		opts.retryBackoff = d
+		return nil
+	}
+}
+
+// ConsumerNoCommitOpt is for a client without a consumer group
+// that tails the topic, the consumer does not commit offsets.
+func ConsumerNoCommitOpt() ConsumerOpt {
+	return func(opts *consumerOpts) error {
+		opts.noCommit = true
+		return nil
+	}
+}
+
 type consumerOpts struct {
-	cl       ConsumerClient
-	receiver port.PaymentReceiver
-	decodeFn func([]byte, any) error
+	cl           ConsumerClient
+	receiver     port.PaymentReceiver
+	decodeFn     func([]byte) (any, error)
+	batchSize    int
+	retryBackoff time.Duration
+	noCommit     bool
 }
 
 type Consumer struct {
 	cl       ConsumerClient
 	receiver port.PaymentReceiver
-	decodeFn func([]byte, any) error
+	decodeFn func([]byte) (any, error)
+	noCommit bool
 	errTimer *time.Timer
+	// tuning is shared by the copies of the consumer
+	// so it can be changed while the consumer runs
+	batchSize    *atomic.Int64
+	retryBackoff *atomic.Int64
+	// lastPoll is unix nanoseconds of the last poll without
+	// a client or broker error, it tells the consumer is alive
+	lastPoll *atomic.Int64
+	// lastRecords is unix nanoseconds of the last fetched
+	// records, it tells the data is fresh
+	lastRecords *atomic.Int64
 }
 
 func NewConsumer(opts ...ConsumerOpt) Consumer {
@@ -72,19 +126,59 @@ func NewConsumer(opts ...ConsumerOpt) Consumer {
 		panic(fmt.Errorf("%s: options not set", op))
 	}
 
-	var options consumerOpts
+	options := consumerOpts{retryBackoff: 1 * time.Second}
 	for _, opt := range opts {
 		if err := opt(&options); err != nil {
 			panic(err) //develop mistake
 		}
 	}
 
-	return Consumer{
-		cl:       options.cl,
-		receiver: options.receiver,
-		decodeFn: options.decodeFn,
-		errTimer: time.NewTimer(0),
+	c := Consumer{
+		cl:           options.cl,
+		receiver:     options.receiver,
+		decodeFn:     options.decodeFn,
+		noCommit:     options.noCommit,
+		errTimer:     time.NewTimer(0),
+		batchSize:    new(atomic.Int64),
+		retryBackoff: new(atomic.Int64),
+		lastPoll:     new(atomic.Int64),
+		lastRecords:  new(atomic.Int64),
 	}
+	c.SetBatchSize(options.batchSize)
+	c.SetRetryBackoff(options.retryBackoff)
+	return c
+}
+
+// SetBatchSize applies to the next poll, see ConsumerBatchSizeOpt.
+func (c Consumer) SetBatchSize(n int) {
+	c.batchSize.Store(int64(max(n, 0)))
+}
+
+// SetRetryBackoff applies to the next failed poll.
+func (c Consumer) SetRetryBackoff(d time.Duration) {
+	if d > 0 {
+		c.retryBackoff.Store(int64(d))
+	}
+}
+
+// LastPoll is the time of the last poll without a client or broker
+// error, zero before the first one. A poll of an idle topic ends
+// after pollIdleTimeout and counts as successful.
+func (c Consumer) LastPoll() time.Time {
+	return timeOf(c.lastPoll)
+}
+
+// LastRecords is the time of the last poll that fetched records,
+// zero before the first one. It is old while the topic is idle.
+func (c Consumer) LastRecords() time.Time {
+	return timeOf(c.lastRecords)
+}
+
+func timeOf(ns *atomic.Int64) time.Time {
+	if n := ns.Load(); n != 0 {
+		return time.Unix(0, n)
+	}
+	return time.Time{}
 }
 
 func (c Consumer) Close() {
@@ -116,6 +210,9 @@ func (c Consumer) Run(ctx context.Context) {
 				log.Error("failed to consume messages", "err", err)
 				c.slowDown()
 			}
+			if c.noCommit {
+				continue
+			}
 			err = c.commit(ctx)
 			if err != nil {
 				log.Error("failed to commit offset", "err", err)
@@ -141,33 +238,41 @@ func (c Consumer) commit(ctx context.Context) error {
 func (c Consumer) consume(ctx context.Context) error {
 	const op = "Consumer.consume"
 
+	// records of the partitions without errors are received,
+	// their offsets are committed anyway
 	fetches, err := c.pollFetches(ctx)
+	if fetches.NumRecords() != 0 {
+		c.lastRecords.Store(time.Now().UnixNano())
+		c.receiver.ReceivePayments(c.toPayments(fetches))
+	}
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
-
-	if fetches.Empty() {
-		return nil
-	}
-
-	payments := c.toPayments(fetches)
-	c.receiver.ReceivePayments(payments)
 	return nil
 }
 
 func (c Consumer) pollFetches(ctx context.Context) (kgo.Fetches, error) {
 	const op = "Consumer.pollFetches"
 
-	fetches := c.cl.PollFetches(ctx)
+	pollCtx, cancel := context.WithTimeout(ctx, pollIdleTimeout)
+	defer cancel()
+
+	fetches := c.cl.PollRecords(pollCtx, int(c.batchSize.Load()))
 	if err := fetches.Err0(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			// the topic is idle
+			c.lastPoll.Store(time.Now().UnixNano())
+			return nil, nil
+		}
 		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
 	err := c.handleErrs(fetches)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return fetches, fmt.Errorf("%s: %w", op, err)
 	}
 
+	c.lastPoll.Store(time.Now().UnixNano())
 	return fetches, nil
 }
 
@@ -190,47 +295,145 @@ func (c Consumer) handleErrs(fetches kgo.Fetches) error {
 
 func (c Consumer) toPayments(
 	fetches kgo.Fetches,
-) []domain.Payment {
+) []domain.PaymentEnvelope {
 	const op = "Consumer.toPayments"
 	log := slog.With("op", op)
 
-	var payments []domain.Payment
+	var payments []domain.PaymentEnvelope
 
 	fetches.EachRecord(func(r *kgo.Record) {
-		schema, err := c.unmarshal(r.Value)
+		schema, status, err := c.unmarshal(r.Value)
 		if err != nil {
 			err = fmt.Errorf("%s: %w", op, err)
+			if errors.Is(err, errUnknownRecord) {
+				log.Debug("skip record", "err", err)
+				return
+			}
 			log.Error("failed to unmarshal value", "err", err)
 			return
 		}
 
-		p := c.toPayment(schema)
-		payments = append(payments, p)
+		md := fromHeaders(r.Headers)
+		p, err := c.toPayment(schema)
+		if err != nil {
+			err = fmt.Errorf("%s: %w", op, err)
+			log.Error("failed to convert value", "err", err)
+			return
+		}
+		if p.CreatedAt.UnixMilli() == 0 {
+			p.CreatedAt = md.CreatedAt
+		}
+		p.Status = status
+		if p.Status == "" {
+			// records produced before lifecycle events are creations
+			p.Status, err = md.EventType.Status()
+		}
+		if err != nil {
+			err = fmt.Errorf("%s: %w", op, err)
+			log.Error("failed to read event type", "err", err)
+			return
+		}
+		e := domain.PaymentEnvelope{Payment: p, Metadata: md}
+		payments = append(payments, e)
 	})
 	return payments
 }
 
-func (c Consumer) unmarshal(v []byte) (schema.PaymentV1, error) {
+var errUnknownRecord = errors.New("unknown record")
+
+// recordMapper converts a decoded value to the latest payment schema,
+// status is the state the record moves the payment to, the empty
+// status is read from the event type header.
+type recordMapper struct {
+	toPayment func(any) (schema.PaymentV2, error)
+	status    domain.Status
+}
+
+// recordMappers are keyed by record name because a topic may carry many
+// record types when a record subject name strategy is used.
+var recordMappers = map[string]recordMapper{
+	schema.PaymentRecordName: {toPayment: toPaymentV2},
+	schema.RefundRecordName: {
+		toPayment: refundToPaymentV2,
+		status:    domain.StatusRefunded,
+	},
+}
+
+func (c Consumer) unmarshal(v []byte) (schema.PaymentV2, domain.Status, error) {
 	const op = "Consumer.unmarshal"
 
-	var s schema.PaymentV1
-	if err := c.decodeFn(v, &s); err != nil {
-		return schema.PaymentV1{}, fmt.Errorf("%s: %w", op, err)
+	decoded, err := c.decodeFn(v)
+	if err != nil {
+		if errors.Is(err, sr.ErrNotRegistered) {
+			err = fmt.Errorf("%w: %w", errUnknownRecord, err)
+		}
+		return schema.PaymentV2{}, "", fmt.Errorf("%s: %w", op, err)
 	}
 
-	return s, nil
+	rec, ok := decoded.(schema.Record)
+	if !ok {
+		err := fmt.Errorf("%w: value type %T", errUnknownRecord, decoded)
+		return schema.PaymentV2{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	mapper, ok := recordMappers[rec.RecordName()]
+	if !ok {
+		err := fmt.Errorf("%w: %q", errUnknownRecord, rec.RecordName())
+		return schema.PaymentV2{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+
+	p, err := mapper.toPayment(decoded)
+	if err != nil {
+		return schema.PaymentV2{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	return p, mapper.status, nil
 }
 
-func (c Consumer) toPayment(s schema.PaymentV1) domain.Payment {
-	return domain.Payment{
-		ID:     s.ID,
-		Name:   s.Name,
-		Amount: s.Amount,
+func toPaymentV2(v any) (schema.PaymentV2, error) {
+	switch s := v.(type) {
+	case *schema.PaymentV2:
+		return *s, nil
+	case *schema.PaymentV1:
+		return schema.MigrateV1(*s), nil
+	default:
+		return schema.PaymentV2{}, fmt.Errorf("unsupported value type %T", v)
+	}
+}
+
+func refundToPaymentV2(v any) (schema.PaymentV2, error) {
+	r, ok := v.(*schema.Refund)
+	if !ok {
+		return schema.PaymentV2{}, fmt.Errorf("unsupported value type %T", v)
+	}
+	return r.Payment(), nil
+}
+
+func (c Consumer) toPayment(s schema.PaymentV2) (domain.Payment, error) {
+	const op = "Consumer.toPayment"
+
+	rat, err := s.AmountRat()
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("%s: %w", op, err)
+	}
+	// decimals wider than the currency minor unit are rounded
+	amount, err := domain.MoneyFromRat(rat, domain.Currency(s.Currency))
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	p := domain.Payment{
+		ID:        s.ID,
+		Name:      s.Name,
+		Amount:    amount,
+		CreatedAt: s.CreatedAt,
+	}
+	if s.Description != nil {
+		p.Description = *s.Description
 	}
+	return p, nil
 }
 
 func (c Consumer) slowDown() {
-	const timeout = 1 * time.Second
-	c.errTimer.Reset(timeout)
+	c.errTimer.Reset(time.Duration(c.retryBackoff.Load()))
 	<-c.errTimer.C
 }
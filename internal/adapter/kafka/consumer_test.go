@@ -0,0 +1,189 @@
+//go:build !integration
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+type stubConsumerClient struct{}
+
+func (stubConsumerClient) PollRecords(context.Context, int) kgo.Fetches   { return nil }
+func (stubConsumerClient) CommitUncommittedOffsets(context.Context) error { return nil }
+func (stubConsumerClient) Close()                                         {}
+
+type discardReceiver struct{}
+
+func (discardReceiver) ReceivePayments([]domain.PaymentEnvelope) {}
+
+func testSerde(t *testing.T) *sr.Serde {
+	t.Helper()
+	serde := new(sr.Serde)
+	for id, rec := range map[int]struct {
+		v      any
+		format func(schema.Format) (schema.Serialization, error)
+	}{
+		1: {schema.PaymentV2{}, schema.PaymentSerialization},
+		3: {schema.Refund{}, schema.RefundSerialization},
+	} {
+		s, err := rec.format(schema.FormatAvro)
+		require.NoError(t, err)
+		serde.Register(id, rec.v, s.EncodingOpts()...)
+	}
+	serde.Register(2, schema.PaymentV1{},
+		sr.EncodeFn(schema.PaymentV1Codec.EncodeFn()),
+		sr.DecodeFn(schema.PaymentV1Codec.DecodeFn()),
+	)
+	return serde
+}
+
+func fetchesOf(records ...*kgo.Record) kgo.Fetches {
+	return kgo.Fetches{{Topics: []kgo.FetchTopic{{
+		Topic:      "payments",
+		Partitions: []kgo.FetchPartition{{Records: records}},
+	}}}}
+}
+
+func TestConsumerDispatchesByRecordName(t *testing.T) {
+	serde := testSerde(t)
+	c := NewConsumer(
+		ConsumerClientOpt(stubConsumerClient{}),
+		ConsumerReceiverOpt(discardReceiver{}),
+		ConsumerDecodeFnOpt(serde.DecodeNew),
+	)
+	createdAt := time.UnixMilli(1_735_689_600_000)
+
+	v2, err := serde.Encode(schema.PaymentV2{
+		ID: "id-2", Name: "ALICE", Amount: big.NewRat(1050, 100),
+		Currency: "USD", CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	v1, err := serde.Encode(schema.PaymentV1{ID: "id-1", Name: "BOB", Amount: 5})
+	require.NoError(t, err)
+	refund, err := serde.Encode(schema.Refund{
+		ID: "id-2", Name: "ALICE", Amount: big.NewRat(1050, 100),
+		Currency: "USD", CreatedAt: createdAt,
+	})
+	require.NoError(t, err)
+	// magic byte and an unknown schema ID
+	unknown := []byte{0, 0, 0, 0, 9, 1}
+
+	payments := c.toPayments(fetchesOf(
+		&kgo.Record{Value: v2, Headers: toHeaders(domain.Metadata{EventType: domain.EventAuthorized})},
+		&kgo.Record{Value: v1, Headers: toHeaders(domain.Metadata{CreatedAt: createdAt})},
+		&kgo.Record{Value: refund},
+		&kgo.Record{Value: unknown},
+	))
+	require.Len(t, payments, 3)
+
+	assert.Equal(t, "id-2", payments[0].Payment.ID)
+	assert.Equal(t, domain.StatusAuthorized, payments[0].Payment.Status)
+	assert.Equal(t, domain.NewMoney(1050, domain.CurrencyUSD), payments[0].Payment.Amount)
+
+	// V1 has no created_at and currency, the header time is used
+	assert.Equal(t, "id-1", payments[1].Payment.ID)
+	assert.Equal(t, domain.StatusCreated, payments[1].Payment.Status)
+	assert.True(t, payments[1].Payment.CreatedAt.Equal(createdAt))
+
+	assert.Equal(t, "id-2", payments[2].Payment.ID)
+	assert.Equal(t, domain.StatusRefunded, payments[2].Payment.Status)
+}
+
+type countingClient struct {
+	stubConsumerClient
+	commits atomic.Int64
+}
+
+func (c *countingClient) PollRecords(context.Context, int) kgo.Fetches {
+	<-time.After(time.Millisecond)
+	return nil
+}
+
+func (c *countingClient) CommitUncommittedOffsets(context.Context) error {
+	c.commits.Add(1)
+	return nil
+}
+
+func TestConsumerNoCommit(t *testing.T) {
+	for _, noCommit := range []bool{false, true} {
+		cl := &countingClient{}
+		opts := []ConsumerOpt{
+			ConsumerClientOpt(cl),
+			ConsumerReceiverOpt(discardReceiver{}),
+			ConsumerDecodeFnOpt(testSerde(t).DecodeNew),
+		}
+		if noCommit {
+			opts = append(opts, ConsumerNoCommitOpt())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		NewConsumer(opts...).Run(ctx)
+		cancel()
+
+		assert.Equal(t, noCommit, cl.commits.Load() == 0, "noCommit %t", noCommit)
+	}
+}
+
+type fetchesClient struct {
+	stubConsumerClient
+	fetches kgo.Fetches
+}
+
+func (c fetchesClient) PollRecords(context.Context, int) kgo.Fetches { return c.fetches }
+
+type recordingReceiver struct{ es []domain.PaymentEnvelope }
+
+func (r *recordingReceiver) ReceivePayments(es []domain.PaymentEnvelope) {
+	r.es = append(r.es, es...)
+}
+
+func TestConsumerLastPollAndRecords(t *testing.T) {
+	serde := testSerde(t)
+	v, err := serde.Encode(schema.PaymentV2{
+		ID: "id-1", Name: "ALICE", Amount: big.NewRat(1, 1),
+		Currency: "USD", CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	newConsumer := func(fs kgo.Fetches) (Consumer, *recordingReceiver) {
+		r := &recordingReceiver{}
+		return NewConsumer(
+			ConsumerClientOpt(fetchesClient{fetches: fs}),
+			ConsumerReceiverOpt(r),
+			ConsumerDecodeFnOpt(serde.DecodeNew),
+		), r
+	}
+
+	// an idle topic: the poll deadline is not an error
+	c, _ := newConsumer(kgo.Fetches{{Topics: []kgo.FetchTopic{{
+		Partitions: []kgo.FetchPartition{{Partition: -1, Err: context.DeadlineExceeded}},
+	}}}})
+	require.NoError(t, c.consume(context.Background()))
+	assert.False(t, c.LastPoll().IsZero())
+	assert.True(t, c.LastRecords().IsZero())
+
+	// a broker error of one partition: the records of the other are received
+	c, r := newConsumer(kgo.Fetches{{Topics: []kgo.FetchTopic{{
+		Topic: "payments",
+		Partitions: []kgo.FetchPartition{
+			{Partition: 0, Records: []*kgo.Record{{Value: v}}},
+			{Partition: 1, Err: errors.New("not leader")},
+		},
+	}}}})
+	assert.Error(t, c.consume(context.Background()))
+	assert.True(t, c.LastPoll().IsZero())
+	assert.False(t, c.LastRecords().IsZero())
+	require.Len(t, r.es, 1)
+	assert.Equal(t, "id-1", r.es[0].Payment.ID)
+}
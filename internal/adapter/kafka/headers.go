@@ -0,0 +1,58 @@
+package kafka
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const (
+	HeaderProducerID    = "producer-id"
+	HeaderCreatedAt     = "created-at"
+	HeaderSchemaSubject = "schema-subject"
+	HeaderSchemaVersion = "schema-version"
+	HeaderContentType   = "content-type"
+	HeaderCorrelationID = "correlation-id"
+	HeaderEventType     = "event-type"
+)
+
+func toHeaders(md domain.Metadata) []kgo.RecordHeader {
+	hs := []kgo.RecordHeader{
+		{Key: HeaderProducerID, Value: []byte(md.ProducerID)},
+		{Key: HeaderCreatedAt, Value: []byte(md.CreatedAt.UTC().Format(time.RFC3339Nano))},
+		{Key: HeaderSchemaSubject, Value: []byte(md.SchemaSubject)},
+		{Key: HeaderSchemaVersion, Value: []byte(strconv.Itoa(md.SchemaVersion))},
+		{Key: HeaderContentType, Value: []byte(md.ContentType)},
+		{Key: HeaderCorrelationID, Value: []byte(md.CorrelationID)},
+		{Key: HeaderEventType, Value: []byte(md.EventType)},
+	}
+	return hs
+}
+
+// fromHeaders reads known headers into metadata,
+// malformed or missing values are left zero.
+func fromHeaders(hs []kgo.RecordHeader) domain.Metadata {
+	var md domain.Metadata
+	for _, h := range hs {
+		v := string(h.Value)
+		switch h.Key {
+		case HeaderProducerID:
+			md.ProducerID = v
+		case HeaderCreatedAt:
+			md.CreatedAt, _ = time.Parse(time.RFC3339Nano, v)
+		case HeaderSchemaSubject:
+			md.SchemaSubject = v
+		case HeaderSchemaVersion:
+			md.SchemaVersion, _ = strconv.Atoi(v)
+		case HeaderContentType:
+			md.ContentType = v
+		case HeaderCorrelationID:
+			md.CorrelationID = v
+		case HeaderEventType:
+			md.EventType = domain.EventType(v)
+		}
+	}
+	return md
+}
@@ -0,0 +1,43 @@
+//go:build !integration
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestHeadersRoundTrip(t *testing.T) {
+	md := domain.Metadata{
+		ProducerID:    "producer-1",
+		CreatedAt:     time.Date(2025, 1, 1, 0, 0, 0, 123, time.UTC),
+		SchemaSubject: "payments-value",
+		SchemaVersion: 2,
+		ContentType:   "application/vnd.confluent.avro",
+		CorrelationID: "corr-1",
+		EventType:     domain.EventAuthorized,
+	}
+	got := fromHeaders(toHeaders(md))
+	assert.True(t, md.CreatedAt.Equal(got.CreatedAt))
+	got.CreatedAt = md.CreatedAt
+	assert.Equal(t, md, got)
+}
+
+func TestHeadersMissingOrGarbled(t *testing.T) {
+	assert.Equal(t, domain.Metadata{}, fromHeaders(nil))
+
+	md := fromHeaders([]kgo.RecordHeader{
+		{Key: HeaderCreatedAt, Value: []byte("yesterday")},
+		{Key: HeaderSchemaVersion, Value: []byte("two")},
+		{Key: HeaderProducerID, Value: []byte("producer-1")},
+		{Key: "unknown", Value: []byte("x")},
+	})
+	assert.True(t, md.CreatedAt.IsZero())
+	assert.Zero(t, md.SchemaVersion)
+	assert.Equal(t, "producer-1", md.ProducerID)
+	assert.Empty(t, md.EventType)
+}
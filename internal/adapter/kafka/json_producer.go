@@ -0,0 +1,54 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// ContentTypeJSON is the content type of schemaless JSON records.
+const ContentTypeJSON = "application/json"
+
+// produceTimeout bounds produceJSON, records are also produced
+// on shutdown after the application context is canceled.
+const produceTimeout = 10 * time.Second
+
+type jsonRecord struct {
+	key   string
+	value any
+}
+
+// produceJSON produces schemaless JSON records to the topic.
+func produceJSON(cl ProducerClient, topic string, jrs []jsonRecord) error {
+	const op = "kafka.produceJSON"
+	log := slog.With("op", op)
+
+	rs := make([]*kgo.Record, 0, len(jrs))
+	for _, jr := range jrs {
+		v, err := json.Marshal(jr.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		rs = append(rs, &kgo.Record{
+			Topic: topic,
+			Key:   []byte(jr.key),
+			Value: v,
+			Headers: []kgo.RecordHeader{
+				{Key: HeaderContentType, Value: []byte(ContentTypeJSON)},
+			},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), produceTimeout)
+	defer cancel()
+
+	if err := cl.ProduceSync(ctx, rs...).FirstErr(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	log.Info("records produced", "topic", topic, "count", len(rs))
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/niksmo/cloud-integration/internal/core/domain"
 	"github.com/niksmo/cloud-integration/internal/core/port"
 	"github.com/niksmo/cloud-integration/pkg/schema"
@@ -42,14 +43,58 @@ func ProducerEncodeFnOpt(encodeFn func(v any) ([]byte, error)) ProducerOpt {
 	}
 }
 
+// ProducerKeyEncodeFnOpt encodes the payment ID key with
+// the key schema, without it the key is the raw payment ID.
+func ProducerKeyEncodeFnOpt(encodeFn func(v any) ([]byte, error)) ProducerOpt {
+	return func(opts *producerOpts) error {
+		if encodeFn != nil {
+			opts.keyEncodeFn = encodeFn
+			return nil
+		}
+		return errors.New("producer key encode func is nil")
+	}
+}
+
+func ProducerIDOpt(id string) ProducerOpt {
+	return func(opts *producerOpts) error {
+		if id != "" {
+			opts.id = id
+			return nil
+		}
+		return errors.New("producer id is empty")
+	}
+}
+
+func ProducerSchemaOpt(subject string, version int, contentType string) ProducerOpt {
+	return func(opts *producerOpts) error {
+		if subject == "" || contentType == "" {
+			return errors.New("producer schema subject or content type is empty")
+		}
+		opts.subject = subject
+		opts.version = version
+		opts.contentType = contentType
+		return nil
+	}
+}
+
 type producerOpts struct {
-	cl       ProducerClient
-	encodeFn func(v any) ([]byte, error)
+	cl          ProducerClient
+	encodeFn    func(v any) ([]byte, error)
+	keyEncodeFn func(v any) ([]byte, error)
+	id          string
+	subject     string
+	version     int
+	contentType string
 }
 
 type Producer struct {
-	cl       ProducerClient
-	encodeFn func(v any) ([]byte, error)
+	cl          ProducerClient
+	encodeFn    func(v any) ([]byte, error)
+	keyEncodeFn func(v any) ([]byte, error)
+	id          string
+	subject     string
+	version     int
+	contentType string
 }
 
 func NewProducer(opts ...ProducerOpt) Producer {
@@ -59,13 +104,21 @@ func NewProducer(opts ...ProducerOpt) Producer {
 		panic(fmt.Errorf("%s: options not set", op))
 	}
 
-	var options producerOpts
+	options := producerOpts{id: uuid.NewString()}
 	for _, opt := range opts {
 		if err := opt(&options); err != nil {
 			panic(err) //develop mistake
 		}
 	}
-	return Producer{options.cl, options.encodeFn}
+	return Producer{
+		cl:          options.cl,
+		encodeFn:    options.encodeFn,
+		keyEncodeFn: options.keyEncodeFn,
+		id:          options.id,
+		subject:     options.subject,
+		version:     options.version,
+		contentType: options.contentType,
+	}
 }
 
 func (p Producer) Close() {
@@ -109,16 +162,48 @@ func (p Producer) createRecord(
 		return kgo.Record{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	r := kgo.Record{Value: v}
+	// the payment ID key keeps lifecycle events
+	// of a payment in one partition and in order
+	r := kgo.Record{
+		Key:     []byte(payment.ID),
+		Value:   v,
+		Headers: toHeaders(p.metadata(payment)),
+	}
+
+	if p.keyEncodeFn != nil {
+		k, err := p.keyEncodeFn(schema.PaymentKey{ID: payment.ID})
+		if err != nil {
+			return kgo.Record{}, fmt.Errorf("%s: key: %w", op, err)
+		}
+		r.Key = k
+	}
 	return r, nil
 }
 
-func (p Producer) toSchema(payment domain.Payment) schema.PaymentV1 {
-	return schema.PaymentV1{
-		ID:     payment.ID,
-		Name:   payment.Name,
-		Amount: payment.Amount,
+func (p Producer) metadata(payment domain.Payment) domain.Metadata {
+	return domain.Metadata{
+		ProducerID:    p.id,
+		CreatedAt:     payment.CreatedAt,
+		SchemaSubject: p.subject,
+		SchemaVersion: p.version,
+		ContentType:   p.contentType,
+		CorrelationID: uuid.NewString(),
+		EventType:     payment.Status.EventType(),
+	}
+}
+
+func (p Producer) toSchema(payment domain.Payment) schema.PaymentV2 {
+	s := schema.PaymentV2{
+		ID:        payment.ID,
+		Name:      payment.Name,
+		Amount:    payment.Amount.Rat(),
+		Currency:  string(payment.Amount.Currency),
+		CreatedAt: payment.CreatedAt,
+	}
+	if payment.Description != "" {
+		s.Description = &payment.Description
 	}
+	return s
 }
 
 func (p Producer) produce(ctx context.Context, r *kgo.Record) error {
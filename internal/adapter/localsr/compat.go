@@ -0,0 +1,100 @@
+package localsr
+
+import (
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+func isTransitive(level sr.CompatibilityLevel) bool {
+	switch level {
+	case sr.CompatBackwardTransitive,
+		sr.CompatForwardTransitive,
+		sr.CompatFullTransitive:
+		return true
+	}
+	return false
+}
+
+// validateSchema parses Avro schemas, other schema types are
+// accepted as is because the registry stand-in has no parser for them.
+func validateSchema(sch sr.Schema) error {
+	if sch.Type != sr.TypeAvro {
+		return nil
+	}
+	if _, err := parseAvro(sch.Schema); err != nil {
+		return errUnprocessable(fmt.Sprintf("Invalid schema: %v", err))
+	}
+	return nil
+}
+
+// checkCompatibility checks the schema against existing schemas by the
+// Avro resolution rules. Protobuf and JSON schemas are always compatible.
+func checkCompatibility(
+	level sr.CompatibilityLevel, sch sr.Schema, existing []sr.Schema,
+) (sr.CheckCompatibilityResult, error) {
+	if level == sr.CompatNone || sch.Type != sr.TypeAvro {
+		return sr.CheckCompatibilityResult{Is: true}, nil
+	}
+
+	next, err := parseAvro(sch.Schema)
+	if err != nil {
+		return sr.CheckCompatibilityResult{}, errUnprocessable(
+			fmt.Sprintf("Invalid schema: %v", err),
+		)
+	}
+
+	var messages []string
+	sc := avro.NewSchemaCompatibility()
+	for _, e := range existing {
+		if e.Type != sr.TypeAvro {
+			messages = append(messages, "schema type differs from "+e.Type.String())
+			continue
+		}
+		prev, err := parseAvro(e.Schema)
+		if err != nil {
+			return sr.CheckCompatibilityResult{}, err
+		}
+
+		if checksBackward(level) {
+			if err := sc.Compatible(next, prev); err != nil {
+				messages = append(messages, "BACKWARD: "+err.Error())
+			}
+		}
+		if checksForward(level) {
+			if err := sc.Compatible(prev, next); err != nil {
+				messages = append(messages, "FORWARD: "+err.Error())
+			}
+		}
+	}
+
+	return sr.CheckCompatibilityResult{
+		Is:       len(messages) == 0,
+		Messages: messages,
+	}, nil
+}
+
+func checksBackward(level sr.CompatibilityLevel) bool {
+	switch level {
+	case sr.CompatBackward, sr.CompatBackwardTransitive,
+		sr.CompatFull, sr.CompatFullTransitive:
+		return true
+	}
+	return false
+}
+
+func checksForward(level sr.CompatibilityLevel) bool {
+	switch level {
+	case sr.CompatForward, sr.CompatForwardTransitive,
+		sr.CompatFull, sr.CompatFullTransitive:
+		return true
+	}
+	return false
+}
+
+// parseAvro uses a cache per call, versions of one record
+// share a name and must not replace each other in a shared cache.
+func parseAvro(text string) (avro.Schema, error) {
+	return avro.ParseWithCache(text, "", &avro.SchemaCache{})
+}
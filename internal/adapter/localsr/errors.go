@@ -0,0 +1,67 @@
+package localsr
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+// apiError is the registry error body, the client maps
+// error_code to the sr package errors.
+type apiError struct {
+	status  int
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func errSubjectNotFound(subject string) error {
+	return &apiError{
+		http.StatusNotFound, sr.ErrSubjectNotFound.Code,
+		fmt.Sprintf("Subject '%s' not found.", subject),
+	}
+}
+
+func errVersionNotFound(subject string, version int) error {
+	return &apiError{
+		http.StatusNotFound, sr.ErrVersionNotFound.Code,
+		fmt.Sprintf("Version %d not found for subject '%s'.", version, subject),
+	}
+}
+
+func errSchemaNotFound() error {
+	return &apiError{
+		http.StatusNotFound, sr.ErrSchemaNotFound.Code, "Schema not found",
+	}
+}
+
+func errCompatibilityNotConfigured(subject string) error {
+	return &apiError{
+		http.StatusNotFound,
+		sr.ErrSubjectLevelCompatibilityNotConfigured.Code,
+		fmt.Sprintf("Subject '%s' does not have subject-level compatibility configured", subject),
+	}
+}
+
+func errIncompatible(messages []string) error {
+	return &apiError{
+		http.StatusConflict, http.StatusConflict,
+		"Schema being registered is incompatible with an earlier schema: " +
+			strings.Join(messages, "; "),
+	}
+}
+
+func errUnprocessable(msg string) error {
+	return &apiError{
+		http.StatusUnprocessableEntity, sr.ErrInvalidSchema.Code, msg,
+	}
+}
+
+func errBadRequest(msg string) error {
+	return &apiError{http.StatusBadRequest, http.StatusBadRequest, msg}
+}
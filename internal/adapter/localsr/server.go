@@ -0,0 +1,357 @@
+// Package localsr is a stand-in for a Confluent compatible schema registry
+// for offline development. It serves the subset of the REST API used by
+// sr.Client and keeps its state in a JSON file on local disk.
+package localsr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+type Server struct {
+	st  *store
+	mux *http.ServeMux
+}
+
+// NewServer opens or creates the registry state in dir.
+func NewServer(dir string) (*Server, error) {
+	const op = "localsr.NewServer"
+
+	st, err := openStore(dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &Server{st: st, mux: http.NewServeMux()}
+	s.routes()
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) routes() {
+	s.mux.HandleFunc("GET /schemas/types", s.schemaTypes)
+	s.mux.HandleFunc("GET /schemas/ids/{id}", s.schemaByID)
+	s.mux.HandleFunc("GET /schemas/ids/{id}/schema", s.schemaTextByID)
+	s.mux.HandleFunc("GET /schemas/ids/{id}/versions", s.schemaVersionsByID)
+	s.mux.HandleFunc("GET /schemas/ids/{id}/subjects", s.subjectsByID)
+
+	s.mux.HandleFunc("GET /subjects", s.subjects)
+	s.mux.HandleFunc("POST /subjects/{subject}", s.lookupSchema)
+	s.mux.HandleFunc("DELETE /subjects/{subject}", s.deleteSubject)
+	s.mux.HandleFunc("GET /subjects/{subject}/versions", s.subjectVersions)
+	s.mux.HandleFunc("POST /subjects/{subject}/versions", s.registerSchema)
+	s.mux.HandleFunc("GET /subjects/{subject}/versions/{version}", s.schemaByVersion)
+	s.mux.HandleFunc("GET /subjects/{subject}/versions/{version}/schema", s.schemaTextByVersion)
+	s.mux.HandleFunc("DELETE /subjects/{subject}/versions/{version}", s.deleteSchema)
+
+	s.mux.HandleFunc("POST /compatibility/subjects/{subject}/versions", s.checkAll)
+	s.mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/{version}", s.checkVersion)
+
+	s.mux.HandleFunc("GET /config", s.getConfig)
+	s.mux.HandleFunc("PUT /config", s.putConfig)
+	s.mux.HandleFunc("GET /config/{subject}", s.getConfig)
+	s.mux.HandleFunc("PUT /config/{subject}", s.putConfig)
+	s.mux.HandleFunc("DELETE /config/{subject}", s.deleteConfig)
+
+	s.mux.HandleFunc("GET /mode", s.getMode)
+	s.mux.HandleFunc("GET /mode/{subject}", s.getMode)
+}
+
+func (s *Server) schemaTypes(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, []sr.SchemaType{sr.TypeAvro, sr.TypeProtobuf, sr.TypeJSON})
+}
+
+func (s *Server) schemaByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sch, err := s.st.schemaByID(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, sch)
+}
+
+func (s *Server) schemaTextByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	sch, err := s.st.schemaByID(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeText(w, sch.Schema)
+}
+
+func (s *Server) schemaVersionsByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	svs, err := s.st.usages(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, svs)
+}
+
+func (s *Server) subjectsByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	svs, err := s.st.usages(id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	subjects := make([]string, 0, len(svs))
+	for _, sv := range svs {
+		if len(subjects) == 0 || subjects[len(subjects)-1] != sv.Subject {
+			subjects = append(subjects, sv.Subject)
+		}
+	}
+	writeJSON(w, subjects)
+}
+
+func (s *Server) subjects(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, s.st.subjects())
+}
+
+func (s *Server) lookupSchema(w http.ResponseWriter, r *http.Request) {
+	sch, err := readSchema(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ss, err := s.st.lookup(r.PathValue("subject"), sch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, ss)
+}
+
+func (s *Server) deleteSubject(w http.ResponseWriter, r *http.Request) {
+	vs, err := s.st.deleteSubject(r.PathValue("subject"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, vs)
+}
+
+func (s *Server) subjectVersions(w http.ResponseWriter, r *http.Request) {
+	vs, err := s.st.versions(r.PathValue("subject"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, vs)
+}
+
+func (s *Server) registerSchema(w http.ResponseWriter, r *http.Request) {
+	const op = "localsr.Server.registerSchema"
+
+	sch, err := readSchema(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	subject := r.PathValue("subject")
+	id, err := s.st.register(subject, sch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	slog.Info("schema registered", "op", op, "subject", subject, "id", id)
+	writeJSON(w, struct {
+		ID int `json:"id"`
+	}{id})
+}
+
+func (s *Server) schemaByVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := pathVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ss, err := s.st.subjectSchema(r.PathValue("subject"), version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, ss)
+}
+
+func (s *Server) schemaTextByVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := pathVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ss, err := s.st.subjectSchema(r.PathValue("subject"), version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeText(w, ss.Schema.Schema)
+}
+
+func (s *Server) deleteSchema(w http.ResponseWriter, r *http.Request) {
+	version, err := pathVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	deleted, err := s.st.deleteVersion(r.PathValue("subject"), version)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, deleted)
+}
+
+func (s *Server) checkAll(w http.ResponseWriter, r *http.Request) {
+	s.check(w, r, -2)
+}
+
+func (s *Server) checkVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := pathVersion(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	s.check(w, r, version)
+}
+
+func (s *Server) check(w http.ResponseWriter, r *http.Request, version int) {
+	sch, err := readSchema(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	res, err := s.st.check(r.PathValue("subject"), version, sch)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if r.URL.Query().Get("verbose") != "true" {
+		res.Messages = nil
+	}
+	writeJSON(w, res)
+}
+
+func (s *Server) getConfig(w http.ResponseWriter, r *http.Request) {
+	defaultToGlobal := r.URL.Query().Get("defaultToGlobal") == "true"
+	level, err := s.st.compatibility(r.PathValue("subject"), defaultToGlobal)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, sr.CompatibilityResult{Level: level})
+}
+
+func (s *Server) putConfig(w http.ResponseWriter, r *http.Request) {
+	var set sr.SetCompatibility
+	if err := json.NewDecoder(r.Body).Decode(&set); err != nil {
+		writeError(w, errUnprocessable(err.Error()))
+		return
+	}
+	if err := s.st.setCompatibility(r.PathValue("subject"), set.Level); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, sr.SetCompatibility{Level: set.Level})
+}
+
+func (s *Server) deleteConfig(w http.ResponseWriter, r *http.Request) {
+	level, err := s.st.resetCompatibility(r.PathValue("subject"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, sr.SetCompatibility{Level: level})
+}
+
+func (s *Server) getMode(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, struct {
+		Mode sr.Mode `json:"mode"`
+	}{sr.ModeReadWrite})
+}
+
+func readSchema(r *http.Request) (sr.Schema, error) {
+	var sch sr.Schema
+	if err := json.NewDecoder(r.Body).Decode(&sch); err != nil {
+		return sr.Schema{}, errUnprocessable(err.Error())
+	}
+	if sch.Schema == "" {
+		return sr.Schema{}, errUnprocessable("Empty schema")
+	}
+	return sch, nil
+}
+
+func pathID(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		return 0, errBadRequest("invalid schema id")
+	}
+	return id, nil
+}
+
+// pathVersion parses the version path value, "latest" is -1.
+func pathVersion(r *http.Request) (int, error) {
+	v := r.PathValue("version")
+	if v == "latest" {
+		return -1, nil
+	}
+	version, err := strconv.Atoi(v)
+	if err != nil || version == 0 || version < -1 {
+		return 0, errUnprocessable("invalid version " + v)
+	}
+	return version, nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", contentType)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write response", "op", "localsr.writeJSON", "err", err)
+	}
+}
+
+func writeText(w http.ResponseWriter, text string) {
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(text))
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = &apiError{
+			http.StatusInternalServerError, sr.ErrStoreError.Code, err.Error(),
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(apiErr.status)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
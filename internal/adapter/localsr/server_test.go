@@ -0,0 +1,127 @@
+//go:build !integration
+
+package localsr
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/niksmo/cloud-integration/pkg/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+func newClient(t *testing.T, dir string) *sr.Client {
+	t.Helper()
+	srv, err := NewServer(dir)
+	require.NoError(t, err)
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	cl, err := sr.NewClient(sr.URLs(ts.URL))
+	require.NoError(t, err)
+	return cl
+}
+
+func TestServer(t *testing.T) {
+	const subject = "payments-value"
+	ctx := context.Background()
+	dir := t.TempDir()
+	cl := newClient(t, dir)
+
+	v1, err := cl.CreateSchema(ctx, subject, schema.PaymentSchemaV1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+
+	v2, err := cl.CreateSchema(ctx, subject, schema.PaymentSchemaV2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, v2.Version)
+
+	t.Run("RegisterSameSchema", func(t *testing.T) {
+		ss, err := cl.CreateSchema(ctx, subject, schema.PaymentSchemaV2)
+		require.NoError(t, err)
+		assert.Equal(t, v2.ID, ss.ID)
+		assert.Equal(t, 2, ss.Version)
+	})
+
+	t.Run("Lookup", func(t *testing.T) {
+		ss, err := cl.LookupSchema(ctx, subject, schema.PaymentSchemaV1)
+		require.NoError(t, err)
+		assert.Equal(t, v1.ID, ss.ID)
+	})
+
+	t.Run("SchemaByID", func(t *testing.T) {
+		s, err := cl.SchemaByID(ctx, v2.ID)
+		require.NoError(t, err)
+		assert.Equal(t, schema.PaymentSchemaTextV2, s.Schema)
+	})
+
+	t.Run("Incompatible", func(t *testing.T) {
+		broken := sr.Schema{Schema: `{
+			"type": "record", "namespace": "transactions", "name": "payment",
+			"fields": [{"name": "id", "type": "long"}]
+		}`}
+		res, err := cl.CheckCompatibility(sr.WithParams(ctx, sr.Verbose), subject, -1, broken)
+		require.NoError(t, err)
+		assert.False(t, res.Is)
+		assert.NotEmpty(t, res.Messages)
+
+		_, err = cl.CreateSchema(ctx, subject, broken)
+		var respErr *sr.ResponseError
+		require.True(t, errors.As(err, &respErr))
+		assert.Equal(t, 409, respErr.StatusCode)
+	})
+
+	t.Run("SubjectNotFound", func(t *testing.T) {
+		_, err := cl.SchemaByVersion(ctx, "missing-value", -1)
+		var respErr *sr.ResponseError
+		require.True(t, errors.As(err, &respErr))
+		assert.Equal(t, sr.ErrSubjectNotFound, respErr.SchemaError())
+	})
+
+	t.Run("Compatibility", func(t *testing.T) {
+		set := sr.SetCompatibility{Level: sr.CompatFullTransitive}
+		res := cl.SetCompatibility(ctx, set, subject)
+		require.NoError(t, res[0].Err)
+
+		res = cl.Compatibility(ctx, subject)
+		require.NoError(t, res[0].Err)
+		assert.Equal(t, sr.CompatFullTransitive, res[0].Level)
+	})
+
+	t.Run("Persisted", func(t *testing.T) {
+		reopened := newClient(t, dir)
+		versions, err := reopened.SubjectVersions(ctx, subject)
+		require.NoError(t, err)
+		assert.Equal(t, []int{1, 2}, versions)
+
+		res := reopened.Compatibility(ctx, subject)
+		require.NoError(t, res[0].Err)
+		assert.Equal(t, sr.CompatFullTransitive, res[0].Level)
+	})
+}
+
+func TestStoreRollbackOnPersistError(t *testing.T) {
+	const subject = "payments-value"
+	dir := t.TempDir()
+	s, err := openStore(dir)
+	require.NoError(t, err)
+
+	// a non-empty directory in place of the state file fails the rename
+	require.NoError(t, os.MkdirAll(filepath.Join(s.path, "busy"), 0o755))
+
+	_, err = s.register(subject, schema.PaymentSchemaV1)
+	require.Error(t, err)
+	assert.Empty(t, s.subjects())
+	assert.Empty(t, s.st.Schemas)
+	assert.Equal(t, 1, s.st.NextID)
+
+	err = s.setCompatibility(subject, sr.CompatNone)
+	require.Error(t, err)
+	_, err = s.compatibility(subject, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,460 @@
+package localsr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+const stateFilename = "registry.json"
+
+type schemaRecord struct {
+	ID         int                  `json:"id"`
+	Type       sr.SchemaType        `json:"type"`
+	Schema     string               `json:"schema"`
+	References []sr.SchemaReference `json:"references,omitempty"`
+}
+
+type versionRecord struct {
+	Version int `json:"version"`
+	ID      int `json:"id"`
+}
+
+type subjectRecord struct {
+	Versions      []versionRecord        `json:"versions"`
+	Compatibility *sr.CompatibilityLevel `json:"compatibility,omitempty"`
+}
+
+type state struct {
+	NextID        int                       `json:"next_id"`
+	Compatibility sr.CompatibilityLevel     `json:"compatibility"`
+	Schemas       map[int]schemaRecord      `json:"schemas"`
+	Subjects      map[string]*subjectRecord `json:"subjects"`
+}
+
+// store keeps the registry state in memory and
+// writes it to the data directory on every change,
+// a change that fails to persist is rolled back.
+type store struct {
+	mu   sync.RWMutex
+	path string
+	st   state
+}
+
+func openStore(dir string) (*store, error) {
+	const op = "localsr.openStore"
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	s := &store{
+		path: filepath.Join(dir, stateFilename),
+		st: state{
+			NextID:        1,
+			Compatibility: sr.CompatBackward,
+			Schemas:       make(map[int]schemaRecord),
+			Subjects:      make(map[string]*subjectRecord),
+		},
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if err := json.Unmarshal(data, &s.st); err != nil {
+		return nil, fmt.Errorf("%s: %q: %w", op, s.path, err)
+	}
+	return s, nil
+}
+
+// persist writes the state to a temporary file and renames it,
+// so a crash never leaves a half written state file.
+func (s *store) persist() error {
+	data, err := json.MarshalIndent(s.st, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *store) subjects() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.st.Subjects))
+	for name, subj := range s.st.Subjects {
+		if len(subj.Versions) != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *store) versions(subject string) ([]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subj, ok := s.st.Subjects[subject]
+	if !ok || len(subj.Versions) == 0 {
+		return nil, errSubjectNotFound(subject)
+	}
+	vs := make([]int, 0, len(subj.Versions))
+	for _, v := range subj.Versions {
+		vs = append(vs, v.Version)
+	}
+	return vs, nil
+}
+
+// subjectSchema returns the subject version, version -1 is the latest.
+func (s *store) subjectSchema(subject string, version int) (sr.SubjectSchema, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.subjectSchemaLocked(subject, version)
+}
+
+func (s *store) subjectSchemaLocked(subject string, version int) (sr.SubjectSchema, error) {
+	subj, ok := s.st.Subjects[subject]
+	if !ok {
+		return sr.SubjectSchema{}, errSubjectNotFound(subject)
+	}
+	if len(subj.Versions) == 0 {
+		return sr.SubjectSchema{}, errVersionNotFound(subject, version)
+	}
+
+	v := subj.Versions[len(subj.Versions)-1]
+	if version != -1 {
+		i := slices.IndexFunc(subj.Versions, func(v versionRecord) bool {
+			return v.Version == version
+		})
+		if i == -1 {
+			return sr.SubjectSchema{}, errVersionNotFound(subject, version)
+		}
+		v = subj.Versions[i]
+	}
+
+	rec := s.st.Schemas[v.ID]
+	return sr.SubjectSchema{
+		Subject: subject,
+		Version: v.Version,
+		ID:      v.ID,
+		Schema:  rec.toSchema(),
+	}, nil
+}
+
+func (s *store) schemaByID(id int) (sr.Schema, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.st.Schemas[id]
+	if !ok {
+		return sr.Schema{}, errSchemaNotFound()
+	}
+	return rec.toSchema(), nil
+}
+
+func (s *store) usages(id int) ([]sr.SubjectVersion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.st.Schemas[id]; !ok {
+		return nil, errSchemaNotFound()
+	}
+	var svs []sr.SubjectVersion
+	for name, subj := range s.st.Subjects {
+		for _, v := range subj.Versions {
+			if v.ID == id {
+				svs = append(svs, sr.SubjectVersion{Subject: name, Version: v.Version})
+			}
+		}
+	}
+	sort.Slice(svs, func(i, j int) bool {
+		if svs[i].Subject != svs[j].Subject {
+			return svs[i].Subject < svs[j].Subject
+		}
+		return svs[i].Version < svs[j].Version
+	})
+	return svs, nil
+}
+
+// lookup returns the subject version that holds the schema.
+func (s *store) lookup(subject string, sch sr.Schema) (sr.SubjectSchema, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subj, ok := s.st.Subjects[subject]
+	if !ok {
+		return sr.SubjectSchema{}, errSubjectNotFound(subject)
+	}
+	for _, v := range subj.Versions {
+		if s.st.Schemas[v.ID].equal(sch) {
+			return s.subjectSchemaLocked(subject, v.Version)
+		}
+	}
+	return sr.SubjectSchema{}, errSchemaNotFound()
+}
+
+// register adds the schema as the next subject version unless the
+// subject already has it, the schema ID is shared between subjects.
+func (s *store) register(subject string, sch sr.Schema) (int, error) {
+	if err := validateSchema(sch); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subj, ok := s.st.Subjects[subject]
+	if !ok {
+		subj = &subjectRecord{}
+	}
+
+	for _, v := range subj.Versions {
+		if s.st.Schemas[v.ID].equal(sch) {
+			return v.ID, nil
+		}
+	}
+
+	res, err := s.checkLocked(subject, subj, sch, false)
+	if err != nil {
+		return 0, err
+	}
+	if !res.Is {
+		return 0, errIncompatible(res.Messages)
+	}
+
+	id, added := s.schemaIDLocked(sch)
+	version := 1
+	if n := len(subj.Versions); n != 0 {
+		version = subj.Versions[n-1].Version + 1
+	}
+	subj.Versions = append(subj.Versions, versionRecord{version, id})
+	s.st.Subjects[subject] = subj
+
+	if err := s.persist(); err != nil {
+		subj.Versions = subj.Versions[:len(subj.Versions)-1]
+		if !ok {
+			delete(s.st.Subjects, subject)
+		}
+		if added {
+			delete(s.st.Schemas, id)
+			s.st.NextID--
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+// schemaIDLocked returns the ID of the schema, added
+// reports that the schema is new and got the next ID.
+func (s *store) schemaIDLocked(sch sr.Schema) (int, bool) {
+	for id, rec := range s.st.Schemas {
+		if rec.equal(sch) {
+			return id, false
+		}
+	}
+	id := s.st.NextID
+	s.st.NextID++
+	s.st.Schemas[id] = schemaRecord{
+		ID:         id,
+		Type:       sch.Type,
+		Schema:     sch.Schema,
+		References: sch.References,
+	}
+	return id, true
+}
+
+func (s *store) deleteSubject(subject string) ([]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subj, ok := s.st.Subjects[subject]
+	if !ok {
+		return nil, errSubjectNotFound(subject)
+	}
+	vs := make([]int, 0, len(subj.Versions))
+	for _, v := range subj.Versions {
+		vs = append(vs, v.Version)
+	}
+	delete(s.st.Subjects, subject)
+	if err := s.persist(); err != nil {
+		s.st.Subjects[subject] = subj
+		return nil, err
+	}
+	return vs, nil
+}
+
+func (s *store) deleteVersion(subject string, version int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ss, err := s.subjectSchemaLocked(subject, version)
+	if err != nil {
+		return 0, err
+	}
+	subj := s.st.Subjects[subject]
+	prev := subj.Versions
+	subj.Versions = slices.DeleteFunc(slices.Clone(prev), func(v versionRecord) bool {
+		return v.Version == ss.Version
+	})
+	if err := s.persist(); err != nil {
+		subj.Versions = prev
+		return 0, err
+	}
+	return ss.Version, nil
+}
+
+// check tests the schema against a subject version,
+// version -2 tests it against all versions.
+func (s *store) check(subject string, version int, sch sr.Schema) (sr.CheckCompatibilityResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subj, ok := s.st.Subjects[subject]
+	if !ok {
+		return sr.CheckCompatibilityResult{}, errSubjectNotFound(subject)
+	}
+
+	if version == -2 {
+		return s.checkLocked(subject, subj, sch, true)
+	}
+
+	ss, err := s.subjectSchemaLocked(subject, version)
+	if err != nil {
+		return sr.CheckCompatibilityResult{}, err
+	}
+	level := s.levelLocked(subj)
+	return checkCompatibility(level, sch, []sr.Schema{ss.Schema})
+}
+
+func (s *store) checkLocked(
+	subject string, subj *subjectRecord, sch sr.Schema, all bool,
+) (sr.CheckCompatibilityResult, error) {
+	level := s.levelLocked(subj)
+	if len(subj.Versions) == 0 {
+		return sr.CheckCompatibilityResult{Is: true}, nil
+	}
+
+	var existing []sr.Schema
+	if all || isTransitive(level) {
+		for _, v := range subj.Versions {
+			existing = append(existing, s.st.Schemas[v.ID].toSchema())
+		}
+	} else {
+		latest := subj.Versions[len(subj.Versions)-1]
+		existing = append(existing, s.st.Schemas[latest.ID].toSchema())
+	}
+	return checkCompatibility(level, sch, existing)
+}
+
+func (s *store) levelLocked(subj *subjectRecord) sr.CompatibilityLevel {
+	if subj != nil && subj.Compatibility != nil {
+		return *subj.Compatibility
+	}
+	return s.st.Compatibility
+}
+
+// compatibility returns the subject level, or the global level for
+// the empty subject. defaultToGlobal is used for subjects without level.
+func (s *store) compatibility(subject string, defaultToGlobal bool) (sr.CompatibilityLevel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if subject == "" {
+		return s.st.Compatibility, nil
+	}
+	subj, ok := s.st.Subjects[subject]
+	if ok && subj.Compatibility != nil {
+		return *subj.Compatibility, nil
+	}
+	if defaultToGlobal {
+		return s.st.Compatibility, nil
+	}
+	return 0, errCompatibilityNotConfigured(subject)
+}
+
+func (s *store) setCompatibility(subject string, level sr.CompatibilityLevel) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if subject == "" {
+		prev := s.st.Compatibility
+		s.st.Compatibility = level
+		if err := s.persist(); err != nil {
+			s.st.Compatibility = prev
+			return err
+		}
+		return nil
+	}
+	subj, ok := s.st.Subjects[subject]
+	if !ok {
+		subj = &subjectRecord{}
+		s.st.Subjects[subject] = subj
+	}
+	prev := subj.Compatibility
+	subj.Compatibility = &level
+	if err := s.persist(); err != nil {
+		subj.Compatibility = prev
+		if !ok {
+			delete(s.st.Subjects, subject)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *store) resetCompatibility(subject string) (sr.CompatibilityLevel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subj, ok := s.st.Subjects[subject]
+	if !ok || subj.Compatibility == nil {
+		return 0, errCompatibilityNotConfigured(subject)
+	}
+	prev := subj.Compatibility
+	subj.Compatibility = nil
+	if err := s.persist(); err != nil {
+		subj.Compatibility = prev
+		return 0, err
+	}
+	return *prev, nil
+}
+
+func (r schemaRecord) toSchema() sr.Schema {
+	return sr.Schema{
+		Schema:     r.Schema,
+		Type:       r.Type,
+		References: r.References,
+	}
+}
+
+func (r schemaRecord) equal(sch sr.Schema) bool {
+	return r.Type == sch.Type && canonical(r.Schema) == canonical(sch.Schema)
+}
+
+// canonical strips insignificant whitespace from JSON schemas,
+// other schema texts are only trimmed.
+func canonical(text string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(text)); err != nil {
+		return strings.TrimSpace(text)
+	}
+	return buf.String()
+}
@@ -3,37 +3,130 @@ package adapter
 import (
 	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
+	"fmt"
 	"log/slog"
+	"math"
 	"math/rand/v2"
+	"slices"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/niksmo/cloud-integration/internal/core/domain"
 	"github.com/niksmo/cloud-integration/internal/core/port"
 )
 
+// maxPending limits payments with an unfinished lifecycle.
+const maxPending = 100
+
+// idleCheck is how often a zero rate is checked again.
+const idleCheck = 100 * time.Millisecond
+
+type GeneratorOpt func(*generatorOpts) error
+
+// GeneratorScenarioOpt replaces the default scenario.
+func GeneratorScenarioOpt(sc Scenario) GeneratorOpt {
+	return func(opts *generatorOpts) error {
+		if err := sc.Validate(); err != nil {
+			return err
+		}
+		opts.scenario = &sc
+		return nil
+	}
+}
+
+// GeneratorInjectProducerOpt sends the malformed payments straight to
+// the producer, the service would reject them before the topic.
+func GeneratorInjectProducerOpt(p port.PaymentProducer) GeneratorOpt {
+	return func(opts *generatorOpts) error {
+		if p == nil {
+			return errors.New("inject producer is nil")
+		}
+		opts.injectTo = p
+		return nil
+	}
+}
+
+type generatorOpts struct {
+	scenario *Scenario
+	injectTo port.PaymentProducer
+}
+
 type PaymentsGenerator struct {
-	service port.PaymentSender
-	tick    time.Duration
-	buf     bytes.Buffer
-	a       []byte
-	cnt     int
+	service  port.PaymentSender
+	injectTo port.PaymentProducer
+	sc       Scenario
+	fromTick bool
+	// rate is changed by SetTick while the generator runs
+	rate       atomic.Pointer[RateConfig]
+	rng        *rand.Rand
+	zipf       *rand.Zipf
+	payers     []string
+	currencies []domain.Currency
+	buf        bytes.Buffer
+	a          []byte
+	cnt        int
+	last       domain.Payment
+	// pending payments get their next lifecycle events later
+	pending []domain.Payment
 }
 
-func NewPaymentsGenerator(s port.PaymentSender, genTick time.Duration) *PaymentsGenerator {
-	return &PaymentsGenerator{
-		service: s,
-		tick:    genTick,
-		a:       []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+// NewPaymentsGenerator runs DefaultScenario(genTick)
+// unless GeneratorScenarioOpt is set.
+func NewPaymentsGenerator(
+	s port.PaymentSender, genTick time.Duration, opts ...GeneratorOpt,
+) *PaymentsGenerator {
+	const op = "NewPaymentsGenerator"
+
+	var options generatorOpts
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
+		}
+	}
+	sc := DefaultScenario(genTick)
+	if options.scenario != nil {
+		sc = *options.scenario
+	}
+
+	seed := sc.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
 	}
+	g := &PaymentsGenerator{
+		service:  s,
+		injectTo: options.injectTo,
+		sc:       sc,
+		fromTick: options.scenario == nil,
+		rng:      rand.New(rand.NewPCG(seed, seed)),
+		a:        []byte("ABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+	}
+	g.rate.Store(&sc.Rate)
+	for _, c := range sc.Currencies {
+		g.currencies = append(g.currencies, domain.Currency(c))
+	}
+	if n := sc.Payers.Count; n > 0 {
+		g.payers = make([]string, n)
+		for i := range g.payers {
+			g.payers[i] = g.randName()
+		}
+		g.zipf = rand.NewZipf(g.rng, sc.Payers.ZipfS, sc.Payers.ZipfV, uint64(n-1))
+	}
+	slog.Info("payments scenario", "op", op, "seed", seed, "scenario", sc)
+	return g
 }
 
+// Run sends payments at the scenario rate until the
+// scenario duration is over or the context is canceled.
 func (g *PaymentsGenerator) Run(ctx context.Context) {
 	const op = "PaymentsGenerator.Run"
 	log := slog.With("op", op)
 
-	ticker := time.NewTicker(g.tick)
-	defer ticker.Stop()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	start := time.Now()
 
 	log.Info("start generate payments in loop")
 	for {
@@ -41,42 +134,201 @@ func (g *PaymentsGenerator) Run(ctx context.Context) {
 		case <-ctx.Done():
 			log.Info("stopped", "totalPayments", g.cnt)
 			return
-		case <-ticker.C:
-			p := g.createRandPayment()
-			err := g.service.SendPayment(ctx, p)
+		case <-timer.C:
+			elapsed := time.Since(start)
+			if g.sc.Duration != 0 && elapsed >= g.sc.Duration {
+				log.Info("scenario is over", "totalPayments", g.cnt)
+				return
+			}
+
+			rate := g.rate.Load().rateAt(elapsed)
+			if rate <= 0 {
+				timer.Reset(idleCheck)
+				continue
+			}
+			timer.Reset(time.Duration(float64(time.Second) / rate))
+
+			p, malformed := g.nextPayment()
+			err := g.send(ctx, p, malformed)
 			if err != nil {
 				if errors.Is(err, context.Canceled) {
 					log.Info("context cancaled")
 					continue
 				}
+				if errors.Is(err, domain.ErrPaymentRejected) {
+					// the service has logged the reasons
+					continue
+				}
 				log.Error("failed to send payment", "err", err)
+				g.forget(p.ID)
 			}
 		}
 	}
 }
 
+// send passes the malformed payments to the inject producer when it is
+// set, so they reach the topic and exercise the consumer validation.
+func (g *PaymentsGenerator) send(ctx context.Context, p domain.Payment, malformed bool) error {
+	if malformed && g.injectTo != nil {
+		return g.injectTo.ProducePayment(ctx, p)
+	}
+	return g.service.SendPayment(ctx, p)
+}
+
+// SetTick changes the rate of the default scenario,
+// a scenario set with GeneratorScenarioOpt keeps its rate.
+func (g *PaymentsGenerator) SetTick(tick time.Duration) {
+	const op = "PaymentsGenerator.SetTick"
+
+	if !g.fromTick {
+		slog.Warn("tick is ignored, the rate is set by the scenario", "op", op)
+		return
+	}
+	if tick <= 0 {
+		return
+	}
+	rate := DefaultScenario(tick).Rate
+	g.rate.Store(&rate)
+}
+
+// nextPayment injects duplicates and malformed payments at the
+// scenario rates, otherwise it creates or advances a payment.
+// It reports whether the payment is malformed.
+func (g *PaymentsGenerator) nextPayment() (domain.Payment, bool) {
+	const op = "PaymentsGenerator.nextPayment"
+
+	x := g.rng.Float64()
+	in := g.sc.Inject
+	switch {
+	case x < in.DuplicateRate && g.last.ID != "":
+		slog.Info("inject duplicate", "op", op, "paymentID", g.last.ID)
+		return g.last, false
+	case x < in.DuplicateRate+in.MalformedRate:
+		p := g.malformed(g.createRandPayment())
+		slog.Info("inject malformed payment", "op", op, "payment", p)
+		return p, true
+	}
+
+	g.last = g.nextLifecycleEvent()
+	return g.last, false
+}
+
+// nextLifecycleEvent either creates a payment or moves a pending one to
+// its next status, so the events of a payment come in a realistic
+// order: created, authorized, then settled and sometimes refunded.
+func (g *PaymentsGenerator) nextLifecycleEvent() domain.Payment {
+	const op = "PaymentsGenerator.nextLifecycleEvent"
+
+	if !g.sc.Lifecycle {
+		return g.createRandPayment()
+	}
+
+	if len(g.pending) == 0 || (len(g.pending) < maxPending && g.rng.IntN(3) == 0) {
+		p := g.createRandPayment()
+		g.pending = append(g.pending, p)
+		return p
+	}
+
+	i := g.rng.IntN(len(g.pending))
+	p, err := g.pending[i].Advance(g.randNextStatus(g.pending[i].Status))
+	if err != nil {
+		panic(fmt.Errorf("%s: %w", op, err)) // develop mistake
+	}
+
+	// most settled payments are never refunded
+	done := p.Status.Final() || (p.Status == domain.StatusSettled && g.rng.IntN(10) != 0)
+	if done {
+		g.pending = slices.Delete(g.pending, i, i+1)
+	} else {
+		g.pending[i] = p
+	}
+	slog.Info("advance payment", "op", op, "paymentID", p.ID, "status", p.Status)
+	return p
+}
+
+// forget drops the payment that failed to be sent, its next
+// lifecycle events would follow an event the topic never got.
+func (g *PaymentsGenerator) forget(id string) {
+	g.pending = slices.DeleteFunc(g.pending, func(p domain.Payment) bool {
+		return p.ID == id
+	})
+	if g.last.ID == id {
+		g.last = domain.Payment{}
+	}
+}
+
+// randNextStatus mostly settles authorized payments, one in ten is refunded.
+func (g *PaymentsGenerator) randNextStatus(s domain.Status) domain.Status {
+	next := s.Next()
+	if s == domain.StatusAuthorized && g.rng.IntN(10) == 0 {
+		return domain.StatusRefunded
+	}
+	return next[0]
+}
+
+// malformed breaks one field of the payment, the currency is kept
+// as the avro schema cannot encode an unknown one.
+func (g *PaymentsGenerator) malformed(p domain.Payment) domain.Payment {
+	switch g.rng.IntN(4) {
+	case 0:
+		p.ID = "not-a-uuid"
+	case 1:
+		p.Name = ""
+	case 2:
+		p.Amount = p.Amount.Neg()
+	default:
+		p.Amount.Minor = 0
+	}
+	return p
+}
+
 func (g *PaymentsGenerator) createRandPayment() domain.Payment {
 	const op = "PaymentsGenerator.createRandPayment"
 	log := slog.With("op", op)
-	p := domain.NewPayment(g.randName(), g.randAmount())
+
+	p := domain.NewPayment(g.randPayer(), g.randAmount(g.randCurrency()))
+	// the scenario seed makes IDs reproducible too
+	p.ID = g.randID()
 	g.cnt++
 	log.Info("generate payment", "payment", p)
 	return p
 }
 
+func (g *PaymentsGenerator) randPayer() string {
+	if g.zipf == nil {
+		return g.randName()
+	}
+	return g.payers[g.zipf.Uint64()]
+}
+
 func (g *PaymentsGenerator) randName() (name string) {
 	const nameSize = 5
 	aSize := len(g.a)
 	for range nameSize {
-		g.buf.WriteByte(g.a[rand.IntN(aSize)])
+		g.buf.WriteByte(g.a[g.rng.IntN(aSize)])
 	}
 	name = g.buf.String()
 	g.buf.Reset()
 	return
 }
 
-func (g PaymentsGenerator) randAmount() float64 {
-	whole := float64(rand.IntN(1_000) + 1)
-	tenth := float64((rand.IntN(100) + 1)) / 100
-	return whole + tenth
+func (g *PaymentsGenerator) randID() string {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], g.rng.Uint64())
+	binary.LittleEndian.PutUint64(b[8:], g.rng.Uint64())
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return uuid.UUID(b).String()
+}
+
+func (g *PaymentsGenerator) randCurrency() domain.Currency {
+	return g.currencies[g.rng.IntN(len(g.currencies))]
+}
+
+// randAmount samples the scenario distribution and
+// rounds it to the currency minor units.
+func (g *PaymentsGenerator) randAmount(c domain.Currency) domain.Money {
+	v := g.sc.Amount.sample(g.rng)
+	minor := int64(math.Round(v * float64(c.MinorUnits())))
+	return domain.NewMoney(max(minor, 1), c)
 }
@@ -0,0 +1,37 @@
+package adapter
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.PaymentReceiver = (*PaymentsWriter)(nil)
+
+// PaymentsWriter writes received payments as JSON lines,
+// the same records as in the HDFS payments files.
+type PaymentsWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func NewPaymentsWriter(w io.Writer) *PaymentsWriter {
+	return &PaymentsWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *PaymentsWriter) ReceivePayments(es []domain.PaymentEnvelope) {
+	const op = "PaymentsWriter.ReceivePayments"
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, e := range es {
+		if err := w.enc.Encode(toPaymentRecord(e)); err != nil {
+			slog.Error("failed to write payment", "op", op, "err", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+// latestVersion is the schema registry alias of the latest subject version.
+const latestVersion = -1
+
+type Client interface {
+	SchemaByVersion(ctx context.Context, subject string, version int) (sr.SubjectSchema, error)
+	LookupSchema(ctx context.Context, subject string, s sr.Schema) (sr.SubjectSchema, error)
+	CreateSchema(ctx context.Context, subject string, s sr.Schema) (sr.SubjectSchema, error)
+	CheckCompatibility(ctx context.Context, subject string, version int, s sr.Schema) (sr.CheckCompatibilityResult, error)
+	SetCompatibility(ctx context.Context, compat sr.SetCompatibility, subjects ...string) []sr.CompatibilityResult
+}
+
+// IncompatibleError is returned when a schema is rejected by the
+// compatibility check against the latest registered version.
+type IncompatibleError struct {
+	Subject  string
+	Version  int
+	Messages []string
+	Diff     string
+}
+
+func (e *IncompatibleError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(
+		&sb, "schema is incompatible with subject %q version %d",
+		e.Subject, e.Version,
+	)
+	for _, m := range e.Messages {
+		sb.WriteString("\n\t" + m)
+	}
+	if e.Diff != "" {
+		fmt.Fprintf(&sb, "\n--- registered (version %d)\n+++ local\n", e.Version)
+		sb.WriteString(e.Diff)
+	}
+	return sb.String()
+}
+
+type RegistrarOpt func(*registrarOpts) error
+
+func RegistrarClientOpt(cl Client) RegistrarOpt {
+	return func(opts *registrarOpts) error {
+		if cl != nil {
+			opts.cl = cl
+			return nil
+		}
+		return errors.New("registry client is nil")
+	}
+}
+
+// RegistrarCompatibilityOpt sets the subject compatibility level before
+// registration. Empty level keeps the level configured in the registry.
+func RegistrarCompatibilityOpt(level string) RegistrarOpt {
+	return func(opts *registrarOpts) error {
+		if level == "" {
+			return nil
+		}
+		var l sr.CompatibilityLevel
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return err
+		}
+		opts.compat = &l
+		return nil
+	}
+}
+
+// RegistrarReadOnlyOpt makes the registrar only look up schemas,
+// nothing is registered or configured in the registry.
+func RegistrarReadOnlyOpt(readOnly bool) RegistrarOpt {
+	return func(opts *registrarOpts) error {
+		opts.readOnly = readOnly
+		return nil
+	}
+}
+
+type registrarOpts struct {
+	cl       Client
+	compat   *sr.CompatibilityLevel
+	readOnly bool
+}
+
+type Registrar struct {
+	cl       Client
+	compat   *sr.CompatibilityLevel
+	readOnly bool
+}
+
+func NewRegistrar(opts ...RegistrarOpt) Registrar {
+	const op = "NewRegistrar"
+
+	if len(opts) == 0 {
+		panic(fmt.Errorf("%s: options not set", op))
+	}
+
+	var options registrarOpts
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
+		}
+	}
+	return Registrar{
+		cl:       options.cl,
+		compat:   options.compat,
+		readOnly: options.readOnly,
+	}
+}
+
+// Register returns the registered subject schema. In read-only mode the
+// schema must already exist, otherwise the subject compatibility level is
+// set, the schema is checked against the latest version and registered.
+func (r Registrar) Register(
+	ctx context.Context, subject string, s sr.Schema,
+) (sr.SubjectSchema, error) {
+	const op = "Registrar.Register"
+	log := slog.With("op", op, "subject", subject)
+
+	if r.readOnly {
+		ss, err := r.Lookup(ctx, subject, s)
+		if err != nil {
+			return sr.SubjectSchema{}, fmt.Errorf("%s: read-only: %w", op, err)
+		}
+		return ss, nil
+	}
+
+	if err := r.setCompatibility(ctx, subject); err != nil {
+		return sr.SubjectSchema{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if err := r.checkCompatibility(ctx, subject, s); err != nil {
+		return sr.SubjectSchema{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	ss, err := r.cl.CreateSchema(ctx, subject, s)
+	if err != nil {
+		return sr.SubjectSchema{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	log.Info("schema registered", "id", ss.ID, "version", ss.Version)
+	return ss, nil
+}
+
+// Lookup returns the subject schema registered for s.
+func (r Registrar) Lookup(
+	ctx context.Context, subject string, s sr.Schema,
+) (sr.SubjectSchema, error) {
+	const op = "Registrar.Lookup"
+
+	ss, err := r.cl.LookupSchema(ctx, subject, s)
+	if err != nil {
+		return sr.SubjectSchema{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return ss, nil
+}
+
+// Get returns the subject schema of the version, zero is the latest.
+func (r Registrar) Get(
+	ctx context.Context, subject string, version int,
+) (sr.SubjectSchema, error) {
+	const op = "Registrar.Get"
+
+	if version == 0 {
+		version = latestVersion
+	}
+	ss, err := r.cl.SchemaByVersion(ctx, subject, version)
+	if err != nil {
+		return sr.SubjectSchema{}, fmt.Errorf("%s: %w", op, err)
+	}
+	return ss, nil
+}
+
+// Diff returns the latest registered schema and its line diff with s,
+// the diff is empty when the schemas are equal. A subject that is not
+// registered yet is diffed with an empty schema.
+func (r Registrar) Diff(
+	ctx context.Context, subject string, s sr.Schema,
+) (sr.SubjectSchema, string, error) {
+	const op = "Registrar.Diff"
+
+	latest, err := r.cl.SchemaByVersion(ctx, subject, latestVersion)
+	if err != nil && !isNotFound(err) {
+		return sr.SubjectSchema{}, "", fmt.Errorf("%s: %w", op, err)
+	}
+	if normalize(latest.Schema.Schema) == normalize(s.Schema) {
+		return latest, "", nil
+	}
+	return latest, diffSchemas(latest.Schema.Schema, s.Schema), nil
+}
+
+func (r Registrar) setCompatibility(ctx context.Context, subject string) error {
+	const op = "Registrar.setCompatibility"
+
+	if r.compat == nil {
+		return nil
+	}
+
+	setCompat := sr.SetCompatibility{Level: *r.compat}
+	for _, res := range r.cl.SetCompatibility(ctx, setCompat, subject) {
+		if res.Err != nil {
+			return fmt.Errorf("%s: %w", op, res.Err)
+		}
+	}
+	slog.Info(
+		"subject compatibility is set",
+		"op", op, "subject", subject, "level", r.compat.String(),
+	)
+	return nil
+}
+
+func (r Registrar) checkCompatibility(
+	ctx context.Context, subject string, s sr.Schema,
+) error {
+	const op = "Registrar.checkCompatibility"
+
+	latest, err := r.cl.SchemaByVersion(ctx, subject, latestVersion)
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	if normalize(latest.Schema.Schema) == normalize(s.Schema) {
+		return nil
+	}
+
+	res, err := r.cl.CheckCompatibility(
+		sr.WithParams(ctx, sr.Verbose), subject, latest.Version, s,
+	)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if res.Is {
+		return nil
+	}
+
+	return fmt.Errorf("%s: %w", op, &IncompatibleError{
+		Subject:  subject,
+		Version:  latest.Version,
+		Messages: res.Messages,
+		Diff:     diffSchemas(latest.Schema.Schema, s.Schema),
+	})
+}
+
+func isNotFound(err error) bool {
+	var respErr *sr.ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+	switch respErr.SchemaError() {
+	case sr.ErrSubjectNotFound, sr.ErrVersionNotFound:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+//go:build !integration
+
+package registry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+type stubClient struct {
+	latest  *sr.SubjectSchema
+	compat  sr.CheckCompatibilityResult
+	created bool
+}
+
+func (c *stubClient) SchemaByVersion(context.Context, string, int) (sr.SubjectSchema, error) {
+	if c.latest == nil {
+		return sr.SubjectSchema{}, &sr.ResponseError{ErrorCode: sr.ErrSubjectNotFound.Code}
+	}
+	return *c.latest, nil
+}
+
+func (c *stubClient) LookupSchema(_ context.Context, subject string, s sr.Schema) (sr.SubjectSchema, error) {
+	if c.latest == nil {
+		return sr.SubjectSchema{}, &sr.ResponseError{ErrorCode: sr.ErrSubjectNotFound.Code}
+	}
+	return *c.latest, nil
+}
+
+func (c *stubClient) CreateSchema(_ context.Context, subject string, s sr.Schema) (sr.SubjectSchema, error) {
+	c.created = true
+	return sr.SubjectSchema{Subject: subject, Version: 2, ID: 2, Schema: s}, nil
+}
+
+func (c *stubClient) CheckCompatibility(context.Context, string, int, sr.Schema) (sr.CheckCompatibilityResult, error) {
+	return c.compat, nil
+}
+
+func (c *stubClient) SetCompatibility(context.Context, sr.SetCompatibility, ...string) []sr.CompatibilityResult {
+	return nil
+}
+
+func TestRegistrar(t *testing.T) {
+	registered := sr.SubjectSchema{
+		Subject: "payments-value", Version: 1, ID: 1,
+		Schema: sr.Schema{Schema: `{"type":"record","name":"p","fields":[{"name":"id","type":"string"}]}`},
+	}
+	local := sr.Schema{Schema: `{"type":"record","name":"p","fields":[{"name":"id","type":"long"}]}`}
+
+	t.Run("RegisterNewSubject", func(t *testing.T) {
+		cl := &stubClient{}
+		r := NewRegistrar(RegistrarClientOpt(cl))
+		ss, err := r.Register(context.Background(), "payments-value", local)
+		require.NoError(t, err)
+		assert.True(t, cl.created)
+		assert.Equal(t, 2, ss.Version)
+	})
+
+	t.Run("Incompatible", func(t *testing.T) {
+		cl := &stubClient{
+			latest: &registered,
+			compat: sr.CheckCompatibilityResult{Messages: []string{"type mismatch"}},
+		}
+		r := NewRegistrar(RegistrarClientOpt(cl))
+		_, err := r.Register(context.Background(), "payments-value", local)
+
+		var incompatible *IncompatibleError
+		require.True(t, errors.As(err, &incompatible))
+		assert.False(t, cl.created)
+		assert.Contains(t, incompatible.Diff, `-       "type": "string"`)
+		assert.Contains(t, incompatible.Diff, `+       "type": "long"`)
+	})
+
+	t.Run("ReadOnlyNotRegistered", func(t *testing.T) {
+		cl := &stubClient{}
+		r := NewRegistrar(RegistrarClientOpt(cl), RegistrarReadOnlyOpt(true))
+		_, err := r.Register(context.Background(), "payments-value", local)
+		require.Error(t, err)
+		assert.False(t, cl.created)
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		r := NewRegistrar(RegistrarClientOpt(&stubClient{latest: &registered}))
+		latest, diff, err := r.Diff(context.Background(), "payments-value", local)
+		require.NoError(t, err)
+		assert.Equal(t, 1, latest.Version)
+		assert.Contains(t, diff, `+       "type": "long"`)
+
+		_, diff, err = r.Diff(context.Background(), "payments-value", registered.Schema)
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+
+	t.Run("InvalidCompatibilityLevel", func(t *testing.T) {
+		assert.Panics(t, func() {
+			NewRegistrar(RegistrarClientOpt(&stubClient{}), RegistrarCompatibilityOpt("SOMETIMES"))
+		})
+	})
+}
+
+func TestSubjectName(t *testing.T) {
+	tests := []struct {
+		strategy SubjectNameStrategy
+		isKey    bool
+		want     string
+	}{
+		{"", false, "payments-value"},
+		{TopicNameStrategy, true, "payments-key"},
+		{RecordNameStrategy, false, "transactions.payment"},
+		{TopicRecordNameStrategy, false, "payments-transactions.payment"},
+	}
+	for _, tt := range tests {
+		got, err := SubjectName(tt.strategy, "payments", "transactions.payment", tt.isKey)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got)
+	}
+
+	_, err := SubjectName("unknown", "payments", "transactions.payment", false)
+	require.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package registry
+
+import "fmt"
+
+// SubjectNameStrategy names the subject a schema is registered under.
+type SubjectNameStrategy string
+
+const (
+	// TopicNameStrategy is "<topic>-key" or "<topic>-value",
+	// one record type per topic.
+	TopicNameStrategy SubjectNameStrategy = "topic"
+	// RecordNameStrategy is "<record name>", record
+	// types share schemas across topics.
+	RecordNameStrategy SubjectNameStrategy = "record"
+	// TopicRecordNameStrategy is "<topic>-<record name>",
+	// many record types per topic.
+	TopicRecordNameStrategy SubjectNameStrategy = "topic_record"
+)
+
+// SubjectName returns the subject of a key or value record schema,
+// empty strategy is TopicNameStrategy.
+func SubjectName(
+	strategy SubjectNameStrategy, topic, recordName string, isKey bool,
+) (string, error) {
+	switch strategy {
+	case TopicNameStrategy, "":
+		if isKey {
+			return topic + "-key", nil
+		}
+		return topic + "-value", nil
+	case RecordNameStrategy:
+		return recordName, nil
+	case TopicRecordNameStrategy:
+		return topic + "-" + recordName, nil
+	default:
+		return "", fmt.Errorf("unsupported subject name strategy %q", strategy)
+	}
+}
@@ -0,0 +1,515 @@
+package adapter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/colinmarc/hdfs/v2"
+	"github.com/google/uuid"
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+	"github.com/niksmo/cloud-integration/pkg/replayfmt"
+)
+
+// Replay file formats.
+const (
+	ReplayCSV   = replayfmt.CSV
+	ReplayJSONL = replayfmt.JSONL
+)
+
+// maxLineSize bounds a JSON line of a replayed file.
+const maxLineSize = 1 << 20
+
+// ReplayMapping names the CSV columns or JSON keys of payment fields,
+// empty names keep the default ones. Only amount is required, a missing
+// id is generated, currency defaults to XXX and status to created.
+type ReplayMapping struct {
+	ID          string
+	Name        string
+	Amount      string
+	Currency    string
+	CreatedAt   string
+	Description string
+	Status      string
+}
+
+// DefaultReplayMapping matches the HDFS payments files.
+func DefaultReplayMapping() ReplayMapping {
+	return ReplayMapping{
+		ID:          "id",
+		Name:        "name",
+		Amount:      "amount",
+		Currency:    "currency",
+		CreatedAt:   "created_at",
+		Description: "description",
+		Status:      "status",
+	}
+}
+
+// openFunc opens the replayed file and returns its size, or -1.
+type openFunc func() (io.ReadCloser, int64, error)
+
+type ReplayOpt func(*replayOpts) error
+
+// ReplayLocalFileOpt replays a file from the local disk,
+// the format is taken from the file extension.
+func ReplayLocalFileOpt(path string) ReplayOpt {
+	return func(opts *replayOpts) error {
+		if path == "" {
+			return errors.New("replay file path is empty")
+		}
+		opts.path = path
+		opts.open = func() (io.ReadCloser, int64, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, 0, err
+			}
+			size := int64(-1)
+			if fi, err := f.Stat(); err == nil {
+				size = fi.Size()
+			}
+			return f, size, nil
+		}
+		return nil
+	}
+}
+
+// ReplayHDFSFileOpt replays a file from HDFS,
+// the format is taken from the file extension.
+func ReplayHDFSFileOpt(cl *hdfs.Client, path string) ReplayOpt {
+	return func(opts *replayOpts) error {
+		if cl == nil || path == "" {
+			return errors.New("replay hdfs client is nil or path is empty")
+		}
+		opts.path = path
+		opts.open = func() (io.ReadCloser, int64, error) {
+			f, err := cl.Open(path)
+			if err != nil {
+				return nil, 0, err
+			}
+			return f, f.Stat().Size(), nil
+		}
+		return nil
+	}
+}
+
+// ReplayReaderOpt replays a stream like stdin, the name is used in logs
+// and the format is set with ReplayFormatOpt.
+func ReplayReaderOpt(name string, rd io.Reader) ReplayOpt {
+	return func(opts *replayOpts) error {
+		if rd == nil {
+			return errors.New("replay reader is nil")
+		}
+		opts.path = name
+		opts.open = func() (io.ReadCloser, int64, error) {
+			return io.NopCloser(rd), -1, nil
+		}
+		return nil
+	}
+}
+
+// ReplayFormatOpt sets the format when the extension does not tell it.
+func ReplayFormatOpt(format string) ReplayOpt {
+	return func(opts *replayOpts) error {
+		if format != ReplayCSV && format != ReplayJSONL {
+			return fmt.Errorf("unknown replay format %q", format)
+		}
+		opts.format = format
+		return nil
+	}
+}
+
+func ReplayMappingOpt(m ReplayMapping) ReplayOpt {
+	return func(opts *replayOpts) error {
+		opts.mapping = m
+		return nil
+	}
+}
+
+// ReplaySpeedOpt plays the payments back with their original gaps
+// divided by speed, zero speed sends them as fast as possible.
+func ReplaySpeedOpt(speed float64) ReplayOpt {
+	return func(opts *replayOpts) error {
+		if speed < 0 {
+			return errors.New("replay speed is negative")
+		}
+		opts.speed = speed
+		return nil
+	}
+}
+
+func ReplayProgressOpt(interval time.Duration) ReplayOpt {
+	return func(opts *replayOpts) error {
+		if interval <= 0 {
+			return errors.New("replay progress interval is not positive")
+		}
+		opts.progress = interval
+		return nil
+	}
+}
+
+type replayOpts struct {
+	path     string
+	open     openFunc
+	format   string
+	mapping  ReplayMapping
+	speed    float64
+	progress time.Duration
+}
+
+// ReplayStats counts replayed lines.
+type ReplayStats struct {
+	Sent    int64
+	Failed  int64
+	Skipped int64
+}
+
+// PaymentsReplayer sends payments read from a CSV or JSON Lines file.
+type PaymentsReplayer struct {
+	service  port.PaymentSender
+	path     string
+	open     openFunc
+	format   string
+	mapping  ReplayMapping
+	speed    float64
+	progress time.Duration
+
+	sent, failed, skipped atomic.Int64
+	read                  atomic.Int64
+}
+
+// NewPaymentsReplayer returns an error when the format is not set
+// and the file extension does not tell it.
+func NewPaymentsReplayer(
+	s port.PaymentSender, opts ...ReplayOpt,
+) (*PaymentsReplayer, error) {
+	const op = "NewPaymentsReplayer"
+
+	options := replayOpts{progress: 10 * time.Second}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) //develop mistake
+		}
+	}
+	if options.open == nil {
+		panic(fmt.Errorf("%s: replay file not set", op)) //develop mistake
+	}
+	if options.format == "" {
+		format, err := replayfmt.Of(options.path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		options.format = format
+	}
+
+	return &PaymentsReplayer{
+		service:  s,
+		path:     options.path,
+		open:     options.open,
+		format:   options.format,
+		mapping:  withDefaults(options.mapping),
+		speed:    options.speed,
+		progress: options.progress,
+	}, nil
+}
+
+// Run sends every payment of the file once. Lines that fail to parse
+// are skipped, the replay stops on the context or a read error.
+func (r *PaymentsReplayer) Run(ctx context.Context) (ReplayStats, error) {
+	const op = "PaymentsReplayer.Run"
+	log := slog.With("op", op, "path", r.path)
+
+	rc, size, err := r.open()
+	if err != nil {
+		return ReplayStats{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rc.Close()
+
+	stopProgress := r.reportProgress(size)
+	defer stopProgress()
+
+	log.Info("start replay", "format", r.format, "speed", r.speed, "size", size)
+	records := r.records(&countingReader{rc, &r.read})
+
+	var prev time.Time
+	for rec, err := range records {
+		if err != nil {
+			if errors.Is(err, errBadLine) {
+				r.skipped.Add(1)
+				log.Warn("skip line", "err", err)
+				continue
+			}
+			return r.stats(), fmt.Errorf("%s: %w", op, err)
+		}
+
+		p, err := r.toPayment(rec)
+		if err != nil {
+			r.skipped.Add(1)
+			log.Warn("skip line", "err", err)
+			continue
+		}
+
+		if err := r.wait(ctx, prev, p.CreatedAt); err != nil {
+			return r.stats(), fmt.Errorf("%s: %w", op, err)
+		}
+		prev = p.CreatedAt
+
+		if err := r.service.SendPayment(ctx, p); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return r.stats(), fmt.Errorf("%s: %w", op, err)
+			}
+			r.failed.Add(1)
+			if !errors.Is(err, domain.ErrPaymentRejected) {
+				log.Error("failed to send payment", "err", err)
+			}
+			continue
+		}
+		r.sent.Add(1)
+	}
+
+	stats := r.stats()
+	log.Info("replay is over", "sent", stats.Sent, "failed", stats.Failed, "skipped", stats.Skipped)
+	return stats, nil
+}
+
+func (r *PaymentsReplayer) stats() ReplayStats {
+	return ReplayStats{r.sent.Load(), r.failed.Load(), r.skipped.Load()}
+}
+
+// wait keeps the original gap between payments, out of order
+// payments and the first one are sent at once.
+func (r *PaymentsReplayer) wait(ctx context.Context, prev, next time.Time) error {
+	if r.speed == 0 || prev.IsZero() || !next.After(prev) {
+		return ctx.Err()
+	}
+	gap := time.Duration(float64(next.Sub(prev)) / r.speed)
+	timer := time.NewTimer(gap)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (r *PaymentsReplayer) reportProgress(size int64) (stop func()) {
+	const op = "PaymentsReplayer.reportProgress"
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(r.progress)
+		defer ticker.Stop()
+		start := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				stats := r.stats()
+				args := []any{
+					"op", op,
+					"sent", stats.Sent, "failed", stats.Failed, "skipped", stats.Skipped,
+					"perSecond", float64(stats.Sent) / time.Since(start).Seconds(),
+				}
+				if size > 0 {
+					pct := 100 * float64(r.read.Load()) / float64(size)
+					args = append(args, "percent", strconv.FormatFloat(pct, 'f', 1, 64))
+				}
+				slog.Info("replay progress", args...)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+var errBadLine = errors.New("bad line")
+
+// records yields every line of the file as field name to value.
+func (r *PaymentsReplayer) records(rd io.Reader) func(func(map[string]string, error) bool) {
+	if r.format == ReplayCSV {
+		return csvRecords(rd)
+	}
+	return jsonlRecords(rd)
+}
+
+func csvRecords(rd io.Reader) func(func(map[string]string, error) bool) {
+	return func(yield func(map[string]string, error) bool) {
+		cr := csv.NewReader(rd)
+		cr.FieldsPerRecord = -1
+		header, err := cr.Read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				yield(nil, fmt.Errorf("csv header: %w", err))
+			}
+			return
+		}
+		for {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			var perr *csv.ParseError
+			if errors.As(err, &perr) {
+				if !yield(nil, fmt.Errorf("%w: %w", errBadLine, err)) {
+					return
+				}
+				continue
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			rec := make(map[string]string, len(header))
+			for i, name := range header {
+				if i < len(row) {
+					rec[strings.TrimSpace(name)] = row[i]
+				}
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+	}
+}
+
+func jsonlRecords(rd io.Reader) func(func(map[string]string, error) bool) {
+	return func(yield func(map[string]string, error) bool) {
+		sc := bufio.NewScanner(rd)
+		sc.Buffer(make([]byte, 64*1024), maxLineSize)
+		line := 0
+		for sc.Scan() {
+			line++
+			data := bytes.TrimSpace(sc.Bytes())
+			if len(data) == 0 {
+				continue
+			}
+			rec, err := jsonRecord(data)
+			if err != nil {
+				err = fmt.Errorf("%w %d: %w", errBadLine, line, err)
+			}
+			if !yield(rec, err) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+func jsonRecord(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	rec := make(map[string]string, len(obj))
+	for k, v := range obj {
+		switch v := v.(type) {
+		case nil:
+		case string:
+			rec[k] = v
+		case json.Number:
+			rec[k] = v.String()
+		default:
+			rec[k] = fmt.Sprint(v)
+		}
+	}
+	return rec, nil
+}
+
+func (r *PaymentsReplayer) toPayment(rec map[string]string) (domain.Payment, error) {
+	m := r.mapping
+
+	amount := strings.TrimSpace(rec[m.Amount])
+	if amount == "" {
+		return domain.Payment{}, fmt.Errorf("%q is empty", m.Amount)
+	}
+	rat, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return domain.Payment{}, fmt.Errorf("%q: invalid decimal %q", m.Amount, amount)
+	}
+
+	currency := domain.CurrencyUnknown
+	if c := strings.TrimSpace(rec[m.Currency]); c != "" {
+		currency = domain.Currency(strings.ToUpper(c))
+	}
+	money, err := domain.MoneyFromRat(rat, currency)
+	if err != nil {
+		return domain.Payment{}, err
+	}
+
+	p := domain.Payment{
+		ID:          rec[m.ID],
+		Name:        rec[m.Name],
+		Amount:      money,
+		Status:      domain.StatusCreated,
+		CreatedAt:   time.Now(),
+		Description: rec[m.Description],
+	}
+	if p.ID == "" {
+		p.ID = uuid.NewString()
+	}
+	if s := rec[m.Status]; s != "" {
+		p.Status = domain.Status(s)
+	}
+	if s := rec[m.CreatedAt]; s != "" {
+		if p.CreatedAt, err = parseTime(s); err != nil {
+			return domain.Payment{}, fmt.Errorf("%q: %w", m.CreatedAt, err)
+		}
+	}
+	return p, nil
+}
+
+// parseTime accepts RFC 3339 or Unix milliseconds.
+func parseTime(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+func withDefaults(m ReplayMapping) ReplayMapping {
+	d := DefaultReplayMapping()
+	pick := func(v, def string) string {
+		if v == "" {
+			return def
+		}
+		return v
+	}
+	return ReplayMapping{
+		ID:          pick(m.ID, d.ID),
+		Name:        pick(m.Name, d.Name),
+		Amount:      pick(m.Amount, d.Amount),
+		Currency:    pick(m.Currency, d.Currency),
+		CreatedAt:   pick(m.CreatedAt, d.CreatedAt),
+		Description: pick(m.Description, d.Description),
+		Status:      pick(m.Status, d.Status),
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,107 @@
+//go:build !integration
+
+package adapter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+	return path
+}
+
+func TestReplayCSVWithMapping(t *testing.T) {
+	path := writeFile(t, "payments.csv", ""+
+		"payment_id,payer,total,currency,created_at\n"+
+		"id-1,ALICE,10.50,usd,2025-01-01T00:00:00Z\n"+
+		"id-2,BOB,not-a-number,RUB,2025-01-01T00:00:01Z\n"+
+		"id-3,CAROL,1000,JPY,1735689602000\n",
+	)
+
+	s := &recordingSender{}
+	r, err := NewPaymentsReplayer(s,
+		ReplayLocalFileOpt(path),
+		ReplayMappingOpt(ReplayMapping{ID: "payment_id", Name: "payer", Amount: "total"}),
+	)
+	require.NoError(t, err)
+	stats, err := r.Run(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, ReplayStats{Sent: 2, Skipped: 1}, stats)
+	require.Len(t, s.ps, 2)
+	assert.Equal(t, "id-1", s.ps[0].ID)
+	assert.Equal(t, "ALICE", s.ps[0].Name)
+	assert.Equal(t, domain.NewMoney(1050, domain.CurrencyUSD), s.ps[0].Amount)
+	assert.Equal(t, domain.StatusCreated, s.ps[0].Status)
+	assert.Equal(t, domain.NewMoney(1000, domain.CurrencyJPY), s.ps[1].Amount)
+	assert.True(t, s.ps[1].CreatedAt.Equal(time.UnixMilli(1735689602000)))
+}
+
+func TestReplayJSONLOriginalTiming(t *testing.T) {
+	path := writeFile(t, "payments.jsonl", ""+
+		`{"id":"id-1","name":"ALICE","amount":1.5,"currency":"EUR","created_at":"2025-01-01T00:00:00Z"}`+"\n"+
+		"\n"+
+		`{"broken"`+"\n"+
+		`{"id":"id-2","name":"ALICE","amount":"2","currency":"EUR","status":"authorized","created_at":"2025-01-01T00:00:01Z"}`+"\n",
+	)
+
+	s := &recordingSender{}
+	r, err := NewPaymentsReplayer(s, ReplayLocalFileOpt(path), ReplaySpeedOpt(10))
+	require.NoError(t, err)
+	start := time.Now()
+	stats, err := r.Run(context.Background())
+	require.NoError(t, err)
+
+	// one second between the payments played ten times faster
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+	assert.Equal(t, ReplayStats{Sent: 2, Skipped: 1}, stats)
+	require.Len(t, s.ps, 2)
+	assert.Equal(t, domain.NewMoney(150, domain.CurrencyEUR), s.ps[0].Amount)
+	assert.Equal(t, domain.StatusAuthorized, s.ps[1].Status)
+}
+
+func TestReplayReaderToPaymentsWriter(t *testing.T) {
+	rd := strings.NewReader(`{"id":"id-1","name":"ALICE","amount":"1.5","currency":"EUR"}` + "\n")
+
+	s := &recordingSender{}
+	r, err := NewPaymentsReplayer(s, ReplayReaderOpt("stdin", rd), ReplayFormatOpt(ReplayJSONL))
+	require.NoError(t, err)
+	stats, err := r.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ReplayStats{Sent: 1}, stats)
+
+	var buf bytes.Buffer
+	NewPaymentsWriter(&buf).ReceivePayments([]domain.PaymentEnvelope{{Payment: s.ps[0]}})
+	assert.Contains(t, buf.String(), `"id":"id-1","name":"ALICE","amount":"1.50","amount_minor":150,"currency":"EUR"`)
+}
+
+func TestReplayUnknownFormat(t *testing.T) {
+	_, err := NewPaymentsReplayer(&recordingSender{}, ReplayLocalFileOpt("payments.xlsx"))
+	assert.Error(t, err)
+}
+
+func TestPaymentRecordKeepsHeaders(t *testing.T) {
+	var buf bytes.Buffer
+	NewPaymentsWriter(&buf).ReceivePayments([]domain.PaymentEnvelope{{
+		Payment: domain.Payment{ID: "id-1", Status: domain.StatusSettled},
+		Metadata: domain.Metadata{
+			ContentType: "application/vnd.confluent.avro",
+			EventType:   domain.StatusSettled.EventType(),
+		},
+	}})
+	assert.Contains(t, buf.String(), `"content_type":"application/vnd.confluent.avro"`)
+	assert.Contains(t, buf.String(), `"event_type":"payment.settled"`)
+}
@@ -0,0 +1,242 @@
+package adapter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"gopkg.in/yaml.v3"
+)
+
+// Rate curves of RateConfig.
+const (
+	CurveConstant = "constant"
+	CurveRamp     = "ramp"
+	CurveBurst    = "burst"
+	CurveSine     = "sine"
+)
+
+// Amount distributions of AmountConfig.
+const (
+	DistUniform   = "uniform"
+	DistNormal    = "normal"
+	DistLogNormal = "lognormal"
+	DistPareto    = "pareto"
+)
+
+// Scenario drives PaymentsGenerator, runs with the same seed
+// generate the same payments in the same order.
+type Scenario struct {
+	// Seed zero picks a random seed.
+	Seed uint64 `yaml:"seed"`
+	// Duration zero runs until the context is canceled.
+	Duration   time.Duration `yaml:"duration"`
+	Rate       RateConfig    `yaml:"rate"`
+	Amount     AmountConfig  `yaml:"amount"`
+	Payers     PayersConfig  `yaml:"payers"`
+	Currencies []string      `yaml:"currencies"`
+	// Lifecycle emits authorized, settled and refunded
+	// events of generated payments.
+	Lifecycle bool         `yaml:"lifecycle"`
+	Inject    InjectConfig `yaml:"inject"`
+}
+
+// RateConfig is payments per second over the scenario time.
+// constant: Base. ramp: Base to Peak over Period, then Peak.
+// burst: Peak for BurstLength every Period, Base otherwise.
+// sine: between Base and Peak with Period.
+type RateConfig struct {
+	Curve       string        `yaml:"curve"`
+	Base        float64       `yaml:"base"`
+	Peak        float64       `yaml:"peak"`
+	Period      time.Duration `yaml:"period"`
+	BurstLength time.Duration `yaml:"burst_length"`
+}
+
+// AmountConfig samples amounts in major units, they are clamped to
+// [Min, Max] and rounded to the currency minor units.
+// uniform: Min to Max. normal: Mean, StdDev.
+// lognormal: Mu, Sigma of the log. pareto: Scale, Shape.
+type AmountConfig struct {
+	Distribution string  `yaml:"distribution"`
+	Min          float64 `yaml:"min"`
+	Max          float64 `yaml:"max"`
+	Mean         float64 `yaml:"mean"`
+	StdDev       float64 `yaml:"stddev"`
+	Mu           float64 `yaml:"mu"`
+	Sigma        float64 `yaml:"sigma"`
+	Scale        float64 `yaml:"scale"`
+	Shape        float64 `yaml:"shape"`
+}
+
+// PayersConfig is a fixed payer population picked with Zipf skew S > 1,
+// zero Count makes a new random payer for every payment.
+type PayersConfig struct {
+	Count int     `yaml:"count"`
+	ZipfS float64 `yaml:"zipf_s"`
+	ZipfV float64 `yaml:"zipf_v"`
+}
+
+// InjectConfig is the share of generated payments that repeat the
+// previous one or are malformed. Malformed payments skip the producer
+// side validation when the generator has an inject producer.
+type InjectConfig struct {
+	DuplicateRate float64 `yaml:"duplicate_rate"`
+	MalformedRate float64 `yaml:"malformed_rate"`
+}
+
+// DefaultScenario generates one payment per tick
+// with uniform amounts and random payers.
+func DefaultScenario(tick time.Duration) Scenario {
+	return Scenario{
+		Rate: RateConfig{Curve: CurveConstant, Base: float64(time.Second) / float64(tick)},
+		Amount: AmountConfig{
+			Distribution: DistUniform, Min: 1.01, Max: 1001,
+		},
+		Currencies: []string{"RUB", "USD", "EUR", "CNY"},
+		Lifecycle:  true,
+	}
+}
+
+func LoadScenario(path string) (Scenario, error) {
+	const op = "adapter.LoadScenario"
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Scenario{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var sc Scenario
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&sc); err != nil {
+		return Scenario{}, fmt.Errorf("%s: %q: %w", op, path, err)
+	}
+	if err := sc.Validate(); err != nil {
+		return Scenario{}, fmt.Errorf("%s: %q: %w", op, path, err)
+	}
+	return sc, nil
+}
+
+func (sc Scenario) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	r := sc.Rate
+	if r.Base < 0 || r.Peak < 0 {
+		add("rate is negative")
+	}
+	switch r.Curve {
+	case CurveConstant:
+		if r.Base <= 0 {
+			add("constant rate needs positive base")
+		}
+	case CurveRamp, CurveSine:
+		if r.Period <= 0 {
+			add("%s rate needs positive period", r.Curve)
+		}
+	case CurveBurst:
+		if r.Period <= 0 || r.BurstLength <= 0 || r.BurstLength > r.Period {
+			add("burst rate needs 0 < burst_length <= period")
+		}
+	default:
+		add("unknown rate curve %q", r.Curve)
+	}
+
+	a := sc.Amount
+	if a.Min < 0 || (a.Max != 0 && a.Max < a.Min) {
+		add("amount needs 0 <= min <= max")
+	}
+	switch a.Distribution {
+	case DistUniform:
+		if a.Max <= a.Min {
+			add("uniform amount needs min < max")
+		}
+	case DistNormal:
+		if a.StdDev <= 0 {
+			add("normal amount needs positive stddev")
+		}
+	case DistLogNormal:
+		if a.Sigma <= 0 {
+			add("lognormal amount needs positive sigma")
+		}
+	case DistPareto:
+		if a.Scale <= 0 || a.Shape <= 0 {
+			add("pareto amount needs positive scale and shape")
+		}
+	default:
+		add("unknown amount distribution %q", a.Distribution)
+	}
+
+	if sc.Payers.Count < 0 {
+		add("payers count is negative")
+	}
+	if sc.Payers.Count > 0 && (sc.Payers.ZipfS <= 1 || sc.Payers.ZipfV < 1) {
+		add("payers need zipf_s > 1 and zipf_v >= 1")
+	}
+
+	if len(sc.Currencies) == 0 {
+		add("currencies are empty")
+	}
+	for _, c := range sc.Currencies {
+		if !domain.Currency(c).Valid() {
+			add("unsupported currency %q", c)
+		}
+	}
+
+	in := sc.Inject
+	if in.DuplicateRate < 0 || in.MalformedRate < 0 || in.DuplicateRate+in.MalformedRate > 1 {
+		add("inject rates need to be in [0, 1] with sum up to 1")
+	}
+	return errors.Join(errs...)
+}
+
+// rateAt returns payments per second at the scenario time.
+func (r RateConfig) rateAt(t time.Duration) float64 {
+	switch r.Curve {
+	case CurveRamp:
+		if t >= r.Period {
+			return r.Peak
+		}
+		return r.Base + (r.Peak-r.Base)*float64(t)/float64(r.Period)
+	case CurveBurst:
+		if t%r.Period < r.BurstLength {
+			return r.Peak
+		}
+		return r.Base
+	case CurveSine:
+		phase := 2 * math.Pi * float64(t%r.Period) / float64(r.Period)
+		return r.Base + (r.Peak-r.Base)*(1+math.Sin(phase))/2
+	default:
+		return r.Base
+	}
+}
+
+// sample returns an amount in major units.
+func (a AmountConfig) sample(rng *rand.Rand) float64 {
+	var v float64
+	switch a.Distribution {
+	case DistNormal:
+		v = a.Mean + a.StdDev*rng.NormFloat64()
+	case DistLogNormal:
+		v = math.Exp(a.Mu + a.Sigma*rng.NormFloat64())
+	case DistPareto:
+		v = a.Scale / math.Pow(1-rng.Float64(), 1/a.Shape)
+	default:
+		v = a.Min + (a.Max-a.Min)*rng.Float64()
+	}
+	if v < a.Min {
+		v = a.Min
+	}
+	if a.Max != 0 && v > a.Max {
+		v = a.Max
+	}
+	return v
+}
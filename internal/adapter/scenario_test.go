@@ -0,0 +1,185 @@
+//go:build !integration
+
+package adapter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateCurves(t *testing.T) {
+	ramp := RateConfig{Curve: CurveRamp, Base: 10, Peak: 20, Period: 10 * time.Second}
+	assert.Equal(t, 10.0, ramp.rateAt(0))
+	assert.Equal(t, 15.0, ramp.rateAt(5*time.Second))
+	assert.Equal(t, 20.0, ramp.rateAt(time.Minute))
+
+	burst := RateConfig{
+		Curve: CurveBurst, Base: 1, Peak: 100,
+		Period: time.Minute, BurstLength: 5 * time.Second,
+	}
+	assert.Equal(t, 100.0, burst.rateAt(61*time.Second))
+	assert.Equal(t, 1.0, burst.rateAt(30*time.Second))
+
+	sine := RateConfig{Curve: CurveSine, Base: 0, Peak: 10, Period: 4 * time.Second}
+	assert.InDelta(t, 5.0, sine.rateAt(0), 1e-9)
+	assert.InDelta(t, 10.0, sine.rateAt(time.Second), 1e-9)
+	assert.InDelta(t, 0.0, sine.rateAt(3*time.Second), 1e-9)
+}
+
+func TestLoadScenario(t *testing.T) {
+	sc, err := LoadScenario(filepath.Join("..", "..", "example.scenario.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), sc.Seed)
+	assert.Equal(t, CurveSine, sc.Rate.Curve)
+	assert.Equal(t, time.Minute, sc.Rate.Period)
+
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+rate: {curve: zigzag}
+amount: {distribution: pareto}
+payers: {count: 10, zipf_s: 1}
+currencies: [ZZZ]
+inject: {duplicate_rate: 0.7, malformed_rate: 0.7}
+`), 0o644))
+	_, err = LoadScenario(path)
+	require.Error(t, err)
+	for _, msg := range []string{"zigzag", "pareto", "zipf_s", "ZZZ", "inject"} {
+		assert.Contains(t, err.Error(), msg)
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("unknown: 1\n"), 0o644))
+	_, err = LoadScenario(path)
+	assert.Error(t, err)
+}
+
+type recordingSender struct{ ps []domain.Payment }
+
+func (s *recordingSender) SendPayment(_ context.Context, p domain.Payment) error {
+	s.ps = append(s.ps, p)
+	return nil
+}
+
+func testScenario() Scenario {
+	return Scenario{
+		Seed:       7,
+		Rate:       RateConfig{Curve: CurveConstant, Base: 1},
+		Amount:     AmountConfig{Distribution: DistLogNormal, Min: 0.01, Mu: 3, Sigma: 1},
+		Payers:     PayersConfig{Count: 50, ZipfS: 1.5, ZipfV: 1},
+		Currencies: []string{"RUB", "JPY"},
+		Lifecycle:  true,
+		Inject:     InjectConfig{DuplicateRate: 0.1, MalformedRate: 0.1},
+	}
+}
+
+func generate(n int) []domain.Payment {
+	g := NewPaymentsGenerator(
+		&recordingSender{}, time.Second, GeneratorScenarioOpt(testScenario()),
+	)
+	ps := make([]domain.Payment, 0, n)
+	for range n {
+		p, _ := g.nextPayment()
+		p.CreatedAt = time.Time{}
+		ps = append(ps, p)
+	}
+	return ps
+}
+
+func TestGeneratorIsReproducible(t *testing.T) {
+	first, second := generate(500), generate(500)
+	assert.Equal(t, first, second)
+
+	payers := make(map[string]int)
+	var duplicates, malformed int
+	for i, p := range first {
+		payers[p.Name]++
+		if i > 0 && p == first[i-1] {
+			duplicates++
+		}
+		if !p.Amount.IsPositive() || p.Name == "" ||
+			p.ID == "not-a-uuid" || !p.Amount.Currency.Valid() {
+			malformed++
+		}
+	}
+	assert.LessOrEqual(t, len(payers), 51, "fixed population and malformed empty names")
+	assert.NotZero(t, duplicates)
+	assert.NotZero(t, malformed)
+}
+
+func TestGeneratorStopsAfterDuration(t *testing.T) {
+	sc := testScenario()
+	sc.Rate.Base = 1000
+	sc.Duration = 50 * time.Millisecond
+	sender := &recordingSender{}
+
+	done := make(chan struct{})
+	go func() {
+		NewPaymentsGenerator(sender, time.Second, GeneratorScenarioOpt(sc)).Run(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("generator did not stop")
+	}
+	assert.NotEmpty(t, sender.ps)
+}
+
+type recordingProducer struct{ recordingSender }
+
+func (p *recordingProducer) ProducePayment(ctx context.Context, pm domain.Payment) error {
+	return p.SendPayment(ctx, pm)
+}
+
+func TestGeneratorInjectsMalformedToProducer(t *testing.T) {
+	sc := testScenario()
+	sc.Rate.Base = 1000
+	sc.Duration = 50 * time.Millisecond
+	sc.Inject = InjectConfig{MalformedRate: 1}
+	sender, producer := &recordingSender{}, &recordingProducer{}
+
+	NewPaymentsGenerator(
+		sender, time.Second,
+		GeneratorScenarioOpt(sc), GeneratorInjectProducerOpt(producer),
+	).Run(context.Background())
+
+	assert.Empty(t, sender.ps, "the service validation is skipped")
+	require.NotEmpty(t, producer.ps)
+	for _, p := range producer.ps {
+		valid := p.Amount.IsPositive() && p.Name != "" &&
+			p.ID != "not-a-uuid" && p.Amount.Currency.Valid()
+		assert.False(t, valid, "%+v", p)
+	}
+}
+
+type failingSender struct{ recordingSender }
+
+func (s *failingSender) SendPayment(ctx context.Context, p domain.Payment) error {
+	_ = s.recordingSender.SendPayment(ctx, p)
+	return errors.New("broker is down")
+}
+
+func TestGeneratorForgetsFailedPayments(t *testing.T) {
+	sc := testScenario()
+	sc.Rate.Base = 1000
+	sc.Duration = 50 * time.Millisecond
+	sc.Inject = InjectConfig{}
+	sender := &failingSender{}
+
+	g := NewPaymentsGenerator(sender, time.Second, GeneratorScenarioOpt(sc))
+	g.Run(context.Background())
+
+	require.NotEmpty(t, sender.ps)
+	assert.Empty(t, g.pending)
+	for _, p := range sender.ps {
+		assert.Equal(t, domain.StatusCreated, p.Status, "no events follow a failed one")
+	}
+}
@@ -0,0 +1,241 @@
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/oauth"
+)
+
+// DefaultRefreshBefore is how long before the expiry a token is
+// refreshed when ClientCredentials.RefreshBefore is zero.
+const DefaultRefreshBefore = time.Minute
+
+const tokenRequestTimeout = 10 * time.Second
+
+// defaultTokenLifetime is used when the token response has no expires_in.
+const defaultTokenLifetime = time.Hour
+
+// refreshRetry is the pause after a failed background refresh.
+const refreshRetry = 5 * time.Second
+
+// ClientCredentials is the OAuth 2.0 client credentials grant,
+// the client authenticates to the token endpoint with HTTP basic auth.
+type ClientCredentials struct {
+	TokenURL string
+	ClientID string
+	// ClientSecret is requested on every token request
+	// so a rotated secret is used.
+	ClientSecret func() (string, error)
+	Scopes       []string
+	// RefreshBefore is how long before the expiry the token is replaced.
+	RefreshBefore time.Duration
+	// HTTPClient is optional.
+	HTTPClient *http.Client
+}
+
+// TokenSource caches the access token and requests a new one when the
+// cached token is about to expire. A failed refresh keeps the cached
+// token until it actually expires. With Run the token is refreshed in
+// the background and requests only wait for the first token.
+type TokenSource struct {
+	cc  ClientCredentials
+	now func() time.Time
+	// background is set while Run refreshes the token
+	background atomic.Bool
+
+	mu        sync.Mutex
+	token     string
+	expiry    time.Time
+	refreshAt time.Time
+}
+
+func NewTokenSource(cc ClientCredentials) *TokenSource {
+	const op = "security.NewTokenSource"
+
+	if cc.TokenURL == "" || cc.ClientID == "" || cc.ClientSecret == nil {
+		panic(fmt.Errorf("%s: token url, client id or secret not set", op)) // develop mistake
+	}
+	if cc.RefreshBefore <= 0 {
+		cc.RefreshBefore = DefaultRefreshBefore
+	}
+	if cc.HTTPClient == nil {
+		cc.HTTPClient = &http.Client{Timeout: tokenRequestTimeout}
+	}
+	return &TokenSource{cc: cc, now: time.Now}
+}
+
+// Token returns a valid access token.
+func (ts *TokenSource) Token(ctx context.Context) (string, error) {
+	const op = "TokenSource.Token"
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	now := ts.now()
+	if ts.token != "" && now.Before(ts.refreshAt) {
+		return ts.token, nil
+	}
+	// Run replaces the token before it expires
+	if ts.background.Load() && ts.token != "" && now.Before(ts.expiry) {
+		return ts.token, nil
+	}
+
+	token, lifetime, err := ts.request(ctx)
+	if err != nil {
+		if ts.token != "" && now.Before(ts.expiry) {
+			slog.Warn("failed to refresh token, the cached one is used", "op", op, "err", err)
+			return ts.token, nil
+		}
+		return "", fmt.Errorf("%s: %w", op, err)
+	}
+	ts.storeLocked(token, lifetime, now)
+	return ts.token, nil
+}
+
+// Run refreshes the token ahead of its expiry until the context is
+// done, a failed refresh is retried while the cached token is used.
+func (ts *TokenSource) Run(ctx context.Context) {
+	const op = "TokenSource.Run"
+
+	ts.background.Store(true)
+	defer ts.background.Store(false)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		wait, err := ts.refresh(ctx)
+		if err != nil && ctx.Err() == nil {
+			slog.Warn("failed to refresh token", "op", op, "err", err, "retryIn", wait)
+		}
+		timer.Reset(wait)
+	}
+}
+
+// refresh requests a new token without holding the lock, so requests
+// keep using the cached token, and returns the time until the next one.
+func (ts *TokenSource) refresh(ctx context.Context) (time.Duration, error) {
+	now := ts.now()
+	ts.mu.Lock()
+	fresh := ts.token != "" && now.Before(ts.refreshAt)
+	refreshAt := ts.refreshAt
+	ts.mu.Unlock()
+	// a request has refreshed the token meanwhile
+	if fresh {
+		return refreshAt.Sub(now), nil
+	}
+
+	token, lifetime, err := ts.request(ctx)
+	if err != nil {
+		return refreshRetry, err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	ts.storeLocked(token, lifetime, now)
+	return ts.refreshAt.Sub(now), nil
+}
+
+func (ts *TokenSource) storeLocked(token string, lifetime time.Duration, now time.Time) {
+	const op = "TokenSource.store"
+
+	// short lived tokens are refreshed in the middle of their lifetime
+	ts.token, ts.expiry = token, now.Add(lifetime)
+	ts.refreshAt = ts.expiry.Add(-min(ts.cc.RefreshBefore, lifetime/2))
+	slog.Debug("token is refreshed", "op", op, "expiry", ts.expiry)
+}
+
+// Mechanism returns the OAUTHBEARER SASL mechanism.
+func (ts *TokenSource) Mechanism() sasl.Mechanism {
+	return oauth.Oauth(func(ctx context.Context) (oauth.Auth, error) {
+		token, err := ts.Token(ctx)
+		return oauth.Auth{Token: token}, err
+	})
+}
+
+// BearerAuth sets the token on HTTP requests.
+func (ts *TokenSource) BearerAuth(req *http.Request) error {
+	token, err := ts.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+var ErrTokenRequest = errors.New("token request failed")
+
+func (ts *TokenSource) request(ctx context.Context) (string, time.Duration, error) {
+	secret, err := ts.cc.ClientSecret()
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(ts.cc.Scopes) != 0 {
+		form.Set("scope", strings.Join(ts.cc.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, ts.cc.TokenURL, strings.NewReader(form.Encode()),
+	)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(ts.cc.ClientID), url.QueryEscape(secret))
+
+	resp, err := ts.cc.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf(
+			"%w: %s: %s", ErrTokenRequest, resp.Status, strings.TrimSpace(string(body)),
+		)
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return "", 0, fmt.Errorf("%w: %w", ErrTokenRequest, err)
+	}
+	if tr.AccessToken == "" {
+		return "", 0, fmt.Errorf("%w: empty access token", ErrTokenRequest)
+	}
+	if tr.TokenType != "" && !strings.EqualFold(tr.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("%w: token type %q", ErrTokenRequest, tr.TokenType)
+	}
+	lifetime := time.Duration(tr.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = defaultTokenLifetime
+	}
+	return tr.AccessToken, lifetime, nil
+}
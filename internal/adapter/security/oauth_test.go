@@ -0,0 +1,141 @@
+//go:build !integration
+
+package security
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubTokenServer issues numbered tokens that live for expiresIn seconds,
+// it fails while down is set.
+type stubTokenServer struct {
+	*httptest.Server
+	issued    atomic.Int64
+	down      atomic.Bool
+	expiresIn int64
+}
+
+func newStubTokenServer(t *testing.T, expiresIn int64) *stubTokenServer {
+	s := &stubTokenServer{expiresIn: expiresIn}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "kafka sr" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("token-%d", s.issued.Add(1)),
+			"token_type":   "Bearer",
+			"expires_in":   s.expiresIn,
+		})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestTokenSource(url, secret string) (*TokenSource, *time.Time) {
+	now := time.Now()
+	ts := NewTokenSource(ClientCredentials{
+		TokenURL:      url,
+		ClientID:      "client",
+		ClientSecret:  func() (string, error) { return secret, nil },
+		Scopes:        []string{"kafka", "sr"},
+		RefreshBefore: time.Minute,
+	})
+	ts.now = func() time.Time { return now }
+	return ts, &now
+}
+
+func TestTokenSourceRefresh(t *testing.T) {
+	srv := newStubTokenServer(t, 600)
+	ts, now := newTestTokenSource(srv.URL, "secret")
+	ctx := context.Background()
+
+	token, err := ts.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// cached until a minute before the expiry
+	*now = now.Add(8 * time.Minute)
+	token, err = ts.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	*now = now.Add(90 * time.Second)
+	token, err = ts.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+
+	// the cached token is used while it is still valid
+	srv.down.Store(true)
+	*now = now.Add(9*time.Minute + 30*time.Second)
+	token, err = ts.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+
+	*now = now.Add(time.Minute)
+	_, err = ts.Token(ctx)
+	assert.ErrorIs(t, err, ErrTokenRequest)
+}
+
+func TestTokenSourceAuth(t *testing.T) {
+	srv := newStubTokenServer(t, 2)
+	ts, _ := newTestTokenSource(srv.URL, "secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/subjects", nil)
+	require.NoError(t, ts.BearerAuth(req))
+	assert.Equal(t, "Bearer token-1", req.Header.Get("Authorization"))
+	assert.Equal(t, MechanismOAuthBearer, ts.Mechanism().Name())
+
+	bad, _ := newTestTokenSource(srv.URL, "wrong")
+	_, err := bad.Token(context.Background())
+	assert.ErrorIs(t, err, ErrTokenRequest)
+}
+
+func TestTokenSourceRunRefreshesAhead(t *testing.T) {
+	srv := newStubTokenServer(t, 2)
+	ts, _ := newTestTokenSource(srv.URL, "secret")
+	ts.now = time.Now
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ts.Run(ctx)
+
+	cached := func() string {
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		return ts.token
+	}
+	require.Eventually(t, func() bool { return cached() == "token-1" }, time.Second, 10*time.Millisecond)
+	token, err := ts.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-1", token)
+
+	// replaced in the middle of the two second lifetime without a request
+	require.Eventually(t, func() bool { return cached() == "token-2" }, 3*time.Second, 10*time.Millisecond)
+	assert.EqualValues(t, 2, srv.issued.Load())
+
+	// requests do not wait for the endpoint while the token is valid
+	srv.down.Store(true)
+	time.Sleep(time.Second)
+	token, err = ts.Token(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+}
@@ -0,0 +1,148 @@
+// Package security builds TLS and SASL settings of the
+// broker and schema registry clients.
+package security
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Security protocols, named as in the Kafka client configs.
+const (
+	ProtocolPlaintext     = "plaintext"
+	ProtocolSSL           = "ssl"
+	ProtocolSASLPlaintext = "sasl_plaintext"
+	ProtocolSASLSSL       = "sasl_ssl"
+)
+
+// SASL mechanisms.
+const (
+	MechanismPlain       = "PLAIN"
+	MechanismScramSHA256 = "SCRAM-SHA-256"
+	MechanismScramSHA512 = "SCRAM-SHA-512"
+	// MechanismOAuthBearer is built by TokenSource.Mechanism.
+	MechanismOAuthBearer = "OAUTHBEARER"
+)
+
+// Schema registry authentications.
+const (
+	AuthNone  = "none"
+	AuthBasic = "basic"
+	// AuthBearer uses TokenSource.BearerAuth.
+	AuthBearer = "bearer"
+)
+
+var ErrUnsupported = errors.New("unsupported SASL mechanism")
+
+// Protocols and Mechanisms list the supported values.
+var (
+	Protocols = []string{
+		ProtocolPlaintext, ProtocolSSL, ProtocolSASLPlaintext, ProtocolSASLSSL,
+	}
+	Mechanisms = []string{
+		MechanismPlain, MechanismScramSHA256, MechanismScramSHA512, MechanismOAuthBearer,
+	}
+	Auths = []string{AuthNone, AuthBasic, AuthBearer}
+)
+
+// UsesTLS reports whether the protocol encrypts connections.
+func UsesTLS(protocol string) bool {
+	return protocol == ProtocolSSL || protocol == ProtocolSASLSSL
+}
+
+// UsesSASL reports whether the protocol authenticates with SASL.
+func UsesSASL(protocol string) bool {
+	return protocol == ProtocolSASLPlaintext || protocol == ProtocolSASLSSL
+}
+
+// TLSConfig describes a TLS client. Without CAFile the system roots
+// are used, CertFile and KeyFile enable mutual TLS.
+type TLSConfig struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	// MinVersion is 1.2 or 1.3, empty is 1.2.
+	MinVersion         string
+	InsecureSkipVerify bool
+}
+
+var tlsVersions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (c TLSConfig) Build() (*tls.Config, error) {
+	const op = "TLSConfig.Build"
+
+	minVersion, ok := tlsVersions[c.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported min TLS version %q", op, c.MinVersion)
+	}
+	cfg := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: c.ServerName,
+		// skipping verification is meant for dev clusters only
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.CAFile != "" {
+		caRootPEM, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if ok := cfg.RootCAs.AppendCertsFromPEM(caRootPEM); !ok {
+			return nil, fmt.Errorf("%s: failed to parse CA PEM %q", op, c.CAFile)
+		}
+	}
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return nil, fmt.Errorf("%s: client cert and key are set together", op)
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// Mechanism returns the password SASL mechanism, the password is
+// requested on every authentication so rotated passwords are used.
+func Mechanism(
+	name, user string, pass func() (string, error),
+) (sasl.Mechanism, error) {
+	const op = "security.Mechanism"
+
+	switch strings.ToUpper(name) {
+	case MechanismPlain:
+		return plain.Plain(func(context.Context) (plain.Auth, error) {
+			p, err := pass()
+			return plain.Auth{User: user, Pass: p}, err
+		}), nil
+	case MechanismScramSHA256, MechanismScramSHA512:
+		authFn := func(context.Context) (scram.Auth, error) {
+			p, err := pass()
+			return scram.Auth{User: user, Pass: p}, err
+		}
+		if strings.EqualFold(name, MechanismScramSHA256) {
+			return scram.Sha256(authFn), nil
+		}
+		return scram.Sha512(authFn), nil
+	default:
+		return nil, fmt.Errorf("%s: %w %q", op, ErrUnsupported, name)
+	}
+}
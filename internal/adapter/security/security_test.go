@@ -0,0 +1,103 @@
+//go:build !integration
+
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCert writes a self-signed certificate and its key.
+func writeCert(t *testing.T) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(
+		certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600,
+	))
+	require.NoError(t, os.WriteFile(
+		keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600,
+	))
+	return certFile, keyFile
+}
+
+func TestTLSConfigBuild(t *testing.T) {
+	cert, key := writeCert(t)
+
+	cfg, err := TLSConfig{
+		CAFile: cert, CertFile: cert, KeyFile: key,
+		ServerName: "broker.local", MinVersion: "1.3",
+	}.Build()
+	require.NoError(t, err)
+	assert.NotNil(t, cfg.RootCAs)
+	assert.Len(t, cfg.Certificates, 1)
+	assert.Equal(t, "broker.local", cfg.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+
+	// system roots without a CA file
+	cfg, err = TLSConfig{InsecureSkipVerify: true}.Build()
+	require.NoError(t, err)
+	assert.Nil(t, cfg.RootCAs)
+	assert.True(t, cfg.InsecureSkipVerify)
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+
+	for _, c := range []TLSConfig{
+		{MinVersion: "1.0"},
+		{CertFile: cert},
+		{CAFile: key},
+		{CAFile: "/missing.pem"},
+	} {
+		_, err := c.Build()
+		assert.Error(t, err, "%+v", c)
+	}
+}
+
+func TestMechanism(t *testing.T) {
+	pass := func() (string, error) { return "secret", nil }
+	for name, want := range map[string]string{
+		MechanismPlain:       "PLAIN",
+		MechanismScramSHA256: "SCRAM-SHA-256",
+		"scram-sha-512":      "SCRAM-SHA-512",
+	} {
+		m, err := Mechanism(name, "user", pass)
+		require.NoError(t, err)
+		assert.Equal(t, want, m.Name())
+	}
+
+	_, err := Mechanism("GSSAPI", "user", pass)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	assert.True(t, UsesTLS(ProtocolSSL))
+	assert.False(t, UsesSASL(ProtocolSSL))
+	assert.True(t, UsesSASL(ProtocolSASLPlaintext))
+	assert.False(t, UsesTLS(ProtocolSASLPlaintext))
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// WindowAggregate sums the payments of one payer in one currency
+// made within the event time window [Start, End).
+type WindowAggregate struct {
+	Payer    string
+	Currency Currency
+	Start    time.Time
+	End      time.Time
+	Count    int64
+	Sum      Money
+	Min      Money
+	Max      Money
+}
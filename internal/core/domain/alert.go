@@ -0,0 +1,15 @@
+package domain
+
+import "time"
+
+// FraudAlert flags a suspicious payment, Evidence holds
+// the values that made the rule trigger.
+type FraudAlert struct {
+	ID         string
+	Rule       string
+	PaymentID  string
+	Payer      string
+	Amount     Money
+	DetectedAt time.Time
+	Evidence   map[string]string
+}
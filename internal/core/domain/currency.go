@@ -0,0 +1,43 @@
+package domain
+
+// Currency is an ISO 4217 alphabetic currency code.
+type Currency string
+
+const (
+	// CurrencyUnknown is the ISO 4217 code for "no currency",
+	// it is used for payments that were created before currencies existed.
+	CurrencyUnknown Currency = "XXX"
+	CurrencyRUB     Currency = "RUB"
+	CurrencyUSD     Currency = "USD"
+	CurrencyEUR     Currency = "EUR"
+	CurrencyGBP     Currency = "GBP"
+	CurrencyCNY     Currency = "CNY"
+	CurrencyJPY     Currency = "JPY"
+	CurrencyCHF     Currency = "CHF"
+	CurrencyKZT     Currency = "KZT"
+	CurrencyBYN     Currency = "BYN"
+	CurrencyTRY     Currency = "TRY"
+	CurrencyAED     Currency = "AED"
+	CurrencyINR     Currency = "INR"
+)
+
+var currencies = []Currency{
+	CurrencyUnknown,
+	CurrencyRUB, CurrencyUSD, CurrencyEUR, CurrencyGBP,
+	CurrencyCNY, CurrencyJPY, CurrencyCHF, CurrencyKZT,
+	CurrencyBYN, CurrencyTRY, CurrencyAED, CurrencyINR,
+}
+
+// Currencies returns all supported currencies.
+func Currencies() []Currency {
+	return append([]Currency(nil), currencies...)
+}
+
+func (c Currency) Valid() bool {
+	for _, v := range currencies {
+		if c == v {
+			return true
+		}
+	}
+	return false
+}
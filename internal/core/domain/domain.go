@@ -1,17 +1,44 @@
 package domain
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Payment struct {
 	ID     string
 	Name   string
-	Amount float64
+	Amount Money
+	// Status is the lifecycle state the payment event moves it to.
+	Status      Status
+	CreatedAt   time.Time
+	Description string
 }
 
-func NewPayment(name string, amount float64) Payment {
+func NewPayment(name string, amount Money) Payment {
 	return Payment{
-		ID:     uuid.NewString(),
-		Name:   name,
-		Amount: amount,
+		ID:        uuid.NewString(),
+		Name:      name,
+		Amount:    amount,
+		Status:    StatusCreated,
+		CreatedAt: time.Now(),
 	}
 }
+
+// Metadata describes where a payment record came from.
+type Metadata struct {
+	ProducerID    string
+	CreatedAt     time.Time
+	SchemaSubject string
+	SchemaVersion int
+	ContentType   string
+	CorrelationID string
+	EventType     EventType
+}
+
+// PaymentEnvelope is a received payment together with its record metadata.
+type PaymentEnvelope struct {
+	Payment  Payment
+	Metadata Metadata
+}
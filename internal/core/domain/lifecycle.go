@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var ErrIllegalTransition = errors.New("illegal status transition")
+
+// Status is the payment lifecycle state.
+type Status string
+
+const (
+	StatusCreated    Status = "created"
+	StatusAuthorized Status = "authorized"
+	StatusSettled    Status = "settled"
+	StatusRefunded   Status = "refunded"
+)
+
+// EventType names the event that moves a payment to a status.
+type EventType string
+
+const (
+	EventCreated    EventType = "payment.created"
+	EventAuthorized EventType = "payment.authorized"
+	EventSettled    EventType = "payment.settled"
+	EventRefunded   EventType = "payment.refunded"
+)
+
+// transitions is keyed by the current status, the empty
+// status is a payment that has not been seen yet.
+var transitions = map[Status][]Status{
+	"":               {StatusCreated},
+	StatusCreated:    {StatusAuthorized},
+	StatusAuthorized: {StatusSettled, StatusRefunded},
+	StatusSettled:    {StatusRefunded},
+	StatusRefunded:   nil,
+}
+
+var eventTypes = map[Status]EventType{
+	StatusCreated:    EventCreated,
+	StatusAuthorized: EventAuthorized,
+	StatusSettled:    EventSettled,
+	StatusRefunded:   EventRefunded,
+}
+
+func (s Status) Valid() bool {
+	_, ok := eventTypes[s]
+	return ok
+}
+
+// Next returns the statuses the payment may move to.
+func (s Status) Next() []Status {
+	return slices.Clone(transitions[s])
+}
+
+// Final reports whether no transition leaves the status.
+func (s Status) Final() bool {
+	return s.Valid() && len(transitions[s]) == 0
+}
+
+func (s Status) CanTransitionTo(to Status) bool {
+	return slices.Contains(transitions[s], to)
+}
+
+// Transition checks the move from one status to another.
+func Transition(from, to Status) error {
+	if !from.CanTransitionTo(to) {
+		return fmt.Errorf("%w: %q to %q", ErrIllegalTransition, from, to)
+	}
+	return nil
+}
+
+func (s Status) EventType() EventType {
+	return eventTypes[s]
+}
+
+// Status returns the status the event moves a payment to,
+// the empty event type is a creation.
+func (e EventType) Status() (Status, error) {
+	if e == "" {
+		return StatusCreated, nil
+	}
+	for s, et := range eventTypes {
+		if et == e {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("unknown event type %q", e)
+}
+
+// Advance returns the payment moved to the status.
+func (p Payment) Advance(to Status) (Payment, error) {
+	if err := Transition(p.Status, to); err != nil {
+		return Payment{}, fmt.Errorf("payment %s: %w", p.ID, err)
+	}
+	p.Status = to
+	return p, nil
+}
@@ -0,0 +1,99 @@
+//go:build !integration
+
+package domain
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		c    Currency
+		want int64
+	}{
+		{"123.45", CurrencyRUB, 12345},
+		{"-0.5", CurrencyUSD, -50},
+		{"7", CurrencyEUR, 700},
+		{"1500", CurrencyJPY, 1500},
+	}
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.in, tt.c)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, NewMoney(tt.want, tt.c), m)
+	}
+
+	for _, in := range []string{"", "1.234", "1,5", "abc", "-", "99999999999999999999"} {
+		_, err := ParseMoney(in, CurrencyUSD)
+		assert.Error(t, err, in)
+	}
+	_, err := ParseMoney("1.5", CurrencyJPY)
+	assert.ErrorIs(t, err, ErrInvalidMoney)
+}
+
+func TestMoneyDecimal(t *testing.T) {
+	assert.Equal(t, "123.45 RUB", NewMoney(12345, CurrencyRUB).String())
+	assert.Equal(t, "0.05", NewMoney(5, CurrencyUSD).Decimal())
+	assert.Equal(t, "-0.05", NewMoney(-5, CurrencyUSD).Decimal())
+	assert.Equal(t, "1500", NewMoney(1500, CurrencyJPY).Decimal())
+}
+
+func TestMoneyFromRat(t *testing.T) {
+	tests := []struct {
+		in   string
+		c    Currency
+		want int64
+	}{
+		{"0.125", CurrencyUSD, 12},
+		{"0.135", CurrencyUSD, 14},
+		{"-0.125", CurrencyUSD, -12},
+		{"0.1", CurrencyUSD, 10},
+		{"2.5", CurrencyJPY, 2},
+		{"3.5", CurrencyJPY, 4},
+	}
+	for _, tt := range tests {
+		r, _ := new(big.Rat).SetString(tt.in)
+		m, err := MoneyFromRat(r, tt.c)
+		require.NoError(t, err, tt.in)
+		assert.Equal(t, tt.want, m.Minor, tt.in)
+	}
+
+	huge, _ := new(big.Rat).SetString("1e30")
+	_, err := MoneyFromRat(huge, CurrencyUSD)
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	a, b := NewMoney(1010, CurrencyRUB), NewMoney(20, CurrencyRUB)
+
+	sum, err := a.Add(b)
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(1030, CurrencyRUB), sum)
+
+	diff, err := b.Sub(a)
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(-990, CurrencyRUB), diff)
+
+	prod, err := a.Mul(3)
+	require.NoError(t, err)
+	assert.Equal(t, NewMoney(3030, CurrencyRUB), prod)
+
+	_, err = a.Add(NewMoney(1, CurrencyUSD))
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+
+	_, err = NewMoney(math.MaxInt64, CurrencyRUB).Add(NewMoney(1, CurrencyRUB))
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+	_, err = NewMoney(math.MinInt64, CurrencyRUB).Sub(NewMoney(1, CurrencyRUB))
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+	_, err = NewMoney(math.MaxInt64/2+1, CurrencyRUB).Mul(2)
+	assert.ErrorIs(t, err, ErrMoneyOverflow)
+
+	c, err := a.Cmp(b)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c)
+}
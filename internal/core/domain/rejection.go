@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrPaymentRejected = errors.New("payment rejected")
+
+// RejectionReason tells which rule rejected a payment field and why.
+type RejectionReason struct {
+	Rule    string
+	Field   string
+	Message string
+}
+
+func (r RejectionReason) String() string {
+	return r.Rule + ": " + r.Field + ": " + r.Message
+}
+
+// RejectedPayment is a payment that failed validation.
+type RejectedPayment struct {
+	Envelope   PaymentEnvelope
+	Reasons    []RejectionReason
+	RejectedAt time.Time
+}
+
+// RejectionError carries the reasons of a rejected payment,
+// it matches ErrPaymentRejected with errors.Is.
+type RejectionError struct {
+	PaymentID string
+	Reasons   []RejectionReason
+}
+
+func (e *RejectionError) Error() string {
+	rs := make([]string, 0, len(e.Reasons))
+	for _, r := range e.Reasons {
+		rs = append(rs, r.String())
+	}
+	return ErrPaymentRejected.Error() + " " + e.PaymentID + ": " + strings.Join(rs, "; ")
+}
+
+func (e *RejectionError) Is(target error) bool {
+	return target == ErrPaymentRejected
+}
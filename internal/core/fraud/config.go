@@ -0,0 +1,73 @@
+package fraud
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaxPayers bounds the state of each rule when MaxPayers is zero.
+const DefaultMaxPayers = 10_000
+
+// Config enables rules with non zero parameters.
+type Config struct {
+	// ZScoreK enables ZScore over the last ZScoreSamples amounts,
+	// at least ZScoreMinSamples are needed to flag a payment.
+	ZScoreK          float64
+	ZScoreMinSamples int
+	ZScoreSamples    int
+	// VelocityLimit enables Velocity within VelocityWindow.
+	VelocityLimit  int
+	VelocityWindow time.Duration
+	// DuplicateWindow enables DuplicateAmount.
+	DuplicateWindow time.Duration
+	MaxPayers       int
+}
+
+func FromConfig(cfg Config) (*Engine, error) {
+	const op = "fraud.FromConfig"
+
+	maxPayers := cfg.MaxPayers
+	if maxPayers == 0 {
+		maxPayers = DefaultMaxPayers
+	}
+	if maxPayers < 0 {
+		return nil, fmt.Errorf("%s: max payers is negative", op)
+	}
+
+	var rules []Rule
+
+	if cfg.ZScoreK != 0 {
+		if cfg.ZScoreK < 0 || cfg.ZScoreMinSamples < 2 ||
+			cfg.ZScoreMinSamples > cfg.ZScoreSamples {
+			return nil, fmt.Errorf(
+				"%s: zscore needs positive k and 2 <= min samples <= samples", op,
+			)
+		}
+		rules = append(rules, NewZScore(
+			cfg.ZScoreK, cfg.ZScoreMinSamples, cfg.ZScoreSamples, maxPayers,
+		))
+	}
+
+	if cfg.VelocityLimit != 0 {
+		if cfg.VelocityLimit < 0 || cfg.VelocityWindow <= 0 {
+			return nil, fmt.Errorf(
+				"%s: velocity needs positive limit and window", op,
+			)
+		}
+		rules = append(rules, NewVelocity(
+			cfg.VelocityLimit, cfg.VelocityWindow, maxPayers,
+		))
+	}
+
+	if cfg.DuplicateWindow < 0 {
+		return nil, fmt.Errorf("%s: duplicate window is negative", op)
+	}
+	if cfg.DuplicateWindow != 0 {
+		rules = append(rules, NewDuplicateAmount(cfg.DuplicateWindow, maxPayers))
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("%s: no rules enabled", op)
+	}
+	return NewEngine(rules...), nil
+}
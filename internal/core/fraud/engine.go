@@ -0,0 +1,52 @@
+// Package fraud flags suspicious payments with stateful rules.
+package fraud
+
+import (
+	"expvar"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.FraudDetector = (*Engine)(nil)
+
+// metrics counts alerts per rule, the keys are "<rule>.alerts".
+var metrics = expvar.NewMap("fraud_detection")
+
+// Engine runs every rule on every payment, rules
+// are not safe for concurrent use so the engine serializes them.
+type Engine struct {
+	mu    sync.Mutex
+	rules []Rule
+}
+
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+func (e *Engine) Inspect(p domain.Payment) []domain.FraudAlert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var alerts []domain.FraudAlert
+	for _, r := range e.rules {
+		evidence := r.Inspect(p)
+		if evidence == nil {
+			continue
+		}
+		metrics.Add(r.Name()+".alerts", 1)
+		alerts = append(alerts, domain.FraudAlert{
+			ID:         uuid.NewString(),
+			Rule:       r.Name(),
+			PaymentID:  p.ID,
+			Payer:      p.Name,
+			Amount:     p.Amount,
+			DetectedAt: time.Now(),
+			Evidence:   evidence,
+		})
+	}
+	return alerts
+}
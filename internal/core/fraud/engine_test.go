@@ -0,0 +1,93 @@
+//go:build !integration
+
+package fraud
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func payment(id int, payer string, minor int64, at time.Duration) domain.Payment {
+	return domain.Payment{
+		ID:        strconv.Itoa(id),
+		Name:      payer,
+		Amount:    domain.NewMoney(minor, domain.CurrencyRUB),
+		Status:    domain.StatusCreated,
+		CreatedAt: t0.Add(at),
+	}
+}
+
+func TestZScore(t *testing.T) {
+	r := NewZScore(3, 5, 10, 10)
+	for i := range 8 {
+		assert.Nil(t, r.Inspect(payment(i, "A", 1000+int64(i%3)*10, 0)))
+	}
+	// another payer has its own statistics
+	assert.Nil(t, r.Inspect(payment(8, "B", 100_000, 0)))
+
+	evidence := r.Inspect(payment(9, "A", 5000, 0))
+	require.NotNil(t, evidence)
+	assert.Equal(t, "50.00", evidence["amount"])
+	assert.Equal(t, "8", evidence["samples"])
+}
+
+func TestVelocity(t *testing.T) {
+	r := NewVelocity(3, time.Minute, 10)
+	for i := range 3 {
+		assert.Nil(t, r.Inspect(payment(i, "A", 100, time.Duration(i)*time.Second)))
+	}
+	evidence := r.Inspect(payment(3, "A", 100, 10*time.Second))
+	require.NotNil(t, evidence)
+	assert.Equal(t, "4", evidence["count"])
+
+	// the window has moved past the earlier payments
+	assert.Nil(t, r.Inspect(payment(4, "A", 100, 2*time.Minute)))
+}
+
+func TestDuplicateAmount(t *testing.T) {
+	r := NewDuplicateAmount(30*time.Second, 10)
+	assert.Nil(t, r.Inspect(payment(1, "A", 100, 0)))
+	assert.Nil(t, r.Inspect(payment(2, "A", 200, time.Second)))
+
+	evidence := r.Inspect(payment(3, "A", 200, 5*time.Second))
+	require.NotNil(t, evidence)
+	assert.Equal(t, "2", evidence["previousPaymentID"])
+	// redelivery of the same payment
+	assert.Nil(t, r.Inspect(payment(3, "A", 200, 5*time.Second)))
+
+	assert.Nil(t, r.Inspect(payment(4, "A", 200, time.Minute)))
+}
+
+func TestRuleStateIsBounded(t *testing.T) {
+	r := NewDuplicateAmount(time.Minute, 2)
+	for i, payer := range []string{"A", "B", "C"} {
+		r.Inspect(payment(i, payer, 100, 0))
+	}
+	assert.Equal(t, 2, r.state.len())
+	// A was evicted, so its repeat is not a duplicate
+	assert.Nil(t, r.Inspect(payment(4, "A", 100, time.Second)))
+}
+
+func TestEngine(t *testing.T) {
+	e, err := FromConfig(Config{DuplicateWindow: time.Minute})
+	require.NoError(t, err)
+
+	assert.Empty(t, e.Inspect(payment(1, "A", 100, 0)))
+	alerts := e.Inspect(payment(2, "A", 100, time.Second))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "duplicate_amount", alerts[0].Rule)
+	assert.Equal(t, "2", alerts[0].PaymentID)
+	assert.NotEmpty(t, alerts[0].ID)
+
+	_, err = FromConfig(Config{})
+	assert.Error(t, err)
+	_, err = FromConfig(Config{ZScoreK: 3, ZScoreMinSamples: 10, ZScoreSamples: 5})
+	assert.Error(t, err)
+}
@@ -0,0 +1,45 @@
+package fraud
+
+import "container/list"
+
+// lru bounds rule state, the least recently used key is evicted first.
+type lru[K comparable, V any] struct {
+	capacity int
+	ll       *list.List
+	items    map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key K
+	val V
+}
+
+func newLRU[K comparable, V any](capacity int) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[K]*list.Element),
+	}
+}
+
+// get returns the value of the key, a new value is created
+// with init and may evict the least recently used key.
+func (c *lru[K, V]) get(key K, init func() V) V {
+	if e, ok := c.items[key]; ok {
+		c.ll.MoveToFront(e)
+		return e.Value.(*lruEntry[K, V]).val
+	}
+
+	v := init()
+	c.items[key] = c.ll.PushFront(&lruEntry[K, V]{key, v})
+	if c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	return v
+}
+
+func (c *lru[K, V]) len() int {
+	return c.ll.Len()
+}
@@ -0,0 +1,190 @@
+package fraud
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+)
+
+// Rule inspects payments one by one and keeps its own state,
+// evidence is nil when the payment is not suspicious.
+type Rule interface {
+	Name() string
+	Inspect(domain.Payment) (evidence map[string]string)
+}
+
+type payerKey struct {
+	payer    string
+	currency domain.Currency
+}
+
+func keyOf(p domain.Payment) payerKey {
+	return payerKey{p.Name, p.Amount.Currency}
+}
+
+// ZScore flags amounts above the payer rolling mean plus k standard
+// deviations of the last samples in the same currency.
+type ZScore struct {
+	k          float64
+	minSamples int
+	samples    int
+	state      *lru[payerKey, *ring]
+}
+
+// NewZScore panics if minSamples is greater than samples,
+// maxPayers bounds the number of payers kept in memory.
+func NewZScore(k float64, minSamples, samples, maxPayers int) *ZScore {
+	if k <= 0 || minSamples < 2 || minSamples > samples || maxPayers <= 0 {
+		panic("fraud.NewZScore: invalid parameters") // develop mistake
+	}
+	return &ZScore{k, minSamples, samples, newLRU[payerKey, *ring](maxPayers)}
+}
+
+func (*ZScore) Name() string { return "amount_zscore" }
+
+func (r *ZScore) Inspect(p domain.Payment) map[string]string {
+	rg := r.state.get(keyOf(p), func() *ring { return newRing(r.samples) })
+	defer rg.push(p.Amount.Float64())
+
+	if rg.len() < r.minSamples {
+		return nil
+	}
+	mean, stddev := rg.stats()
+	amount := p.Amount.Float64()
+	threshold := mean + r.k*stddev
+	if amount <= threshold {
+		return nil
+	}
+	return map[string]string{
+		"amount":    p.Amount.Decimal(),
+		"mean":      formatFloat(mean),
+		"stddev":    formatFloat(stddev),
+		"k":         formatFloat(r.k),
+		"threshold": formatFloat(threshold),
+		"samples":   strconv.Itoa(rg.len()),
+	}
+}
+
+// Velocity flags a payer making more than limit payments within
+// the event time window.
+type Velocity struct {
+	limit  int
+	window time.Duration
+	state  *lru[string, *[]time.Time]
+}
+
+func NewVelocity(limit int, window time.Duration, maxPayers int) *Velocity {
+	if limit <= 0 || window <= 0 || maxPayers <= 0 {
+		panic("fraud.NewVelocity: invalid parameters") // develop mistake
+	}
+	return &Velocity{limit, window, newLRU[string, *[]time.Time](maxPayers)}
+}
+
+func (*Velocity) Name() string { return "payer_velocity" }
+
+func (r *Velocity) Inspect(p domain.Payment) map[string]string {
+	ts := r.state.get(p.Name, func() *[]time.Time { return new([]time.Time) })
+
+	since := p.CreatedAt.Add(-r.window)
+	kept := (*ts)[:0]
+	for _, t := range *ts {
+		if t.After(since) {
+			kept = append(kept, t)
+		}
+	}
+	kept = append(kept, p.CreatedAt)
+	// at most limit+1 timestamps are needed to tell the limit is exceeded
+	if len(kept) > r.limit+1 {
+		kept = kept[len(kept)-r.limit-1:]
+	}
+	*ts = kept
+
+	if len(kept) <= r.limit {
+		return nil
+	}
+	return map[string]string{
+		"count":  strconv.Itoa(len(kept)),
+		"limit":  strconv.Itoa(r.limit),
+		"window": r.window.String(),
+	}
+}
+
+// DuplicateAmount flags a payer repeating the same amount
+// within the event time interval, a redelivered payment
+// with the same ID is not a duplicate.
+type DuplicateAmount struct {
+	within time.Duration
+	state  *lru[payerKey, *domain.Payment]
+}
+
+func NewDuplicateAmount(within time.Duration, maxPayers int) *DuplicateAmount {
+	if within <= 0 || maxPayers <= 0 {
+		panic("fraud.NewDuplicateAmount: invalid parameters") // develop mistake
+	}
+	return &DuplicateAmount{within, newLRU[payerKey, *domain.Payment](maxPayers)}
+}
+
+func (*DuplicateAmount) Name() string { return "duplicate_amount" }
+
+func (r *DuplicateAmount) Inspect(p domain.Payment) map[string]string {
+	last := r.state.get(keyOf(p), func() *domain.Payment { return new(domain.Payment) })
+	prev := *last
+	*last = p
+
+	if prev.ID == "" || prev.ID == p.ID || prev.Amount != p.Amount {
+		return nil
+	}
+	interval := p.CreatedAt.Sub(prev.CreatedAt)
+	if interval.Abs() > r.within {
+		return nil
+	}
+	return map[string]string{
+		"amount":            p.Amount.Decimal(),
+		"previousPaymentID": prev.ID,
+		"interval":          interval.String(),
+	}
+}
+
+// ring keeps the last samples of a payer.
+type ring struct {
+	buf  []float64
+	next int
+	full bool
+}
+
+func newRing(size int) *ring {
+	return &ring{buf: make([]float64, size)}
+}
+
+func (r *ring) push(v float64) {
+	r.buf[r.next] = v
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *ring) len() int {
+	if r.full {
+		return len(r.buf)
+	}
+	return r.next
+}
+
+func (r *ring) stats() (mean, stddev float64) {
+	n := r.len()
+	for _, v := range r.buf[:n] {
+		mean += v
+	}
+	mean /= float64(n)
+	for _, v := range r.buf[:n] {
+		stddev += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(stddev / float64(n))
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
@@ -0,0 +1,89 @@
+// Package lifecycle keeps the current status of received payments.
+package lifecycle
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.PaymentStateTracker = (*Tracker)(nil)
+
+// DefaultCapacity is the number of payment IDs a tracker remembers.
+const DefaultCapacity = 100_000
+
+// Tracker keeps the current status per payment ID. It remembers at most
+// capacity IDs, the first seen IDs are forgotten first. The state is in
+// memory only and is lost on restart, so an unknown ID is taken as
+// a payment seen for the first time whatever its status is.
+type Tracker struct {
+	mu       sync.Mutex
+	capacity int
+	statuses map[string]domain.Status
+	order    []string
+}
+
+func NewTracker(capacity int) *Tracker {
+	const op = "lifecycle.NewTracker"
+
+	if capacity <= 0 {
+		panic(fmt.Errorf("%s: capacity must be positive", op)) // develop mistake
+	}
+	return &Tracker{
+		capacity: capacity,
+		statuses: make(map[string]domain.Status),
+	}
+}
+
+// Apply moves the payment to the status, it returns
+// domain.ErrIllegalTransition and keeps the current status
+// when the transition table does not allow the move.
+// A redelivered status of the payment is not an error.
+func (t *Tracker) Apply(id string, to domain.Status) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !to.Valid() {
+		return fmt.Errorf("%w: unknown status %q", domain.ErrIllegalTransition, to)
+	}
+	from, seen := t.statuses[id]
+	if from == to {
+		return nil
+	}
+	if seen {
+		if err := domain.Transition(from, to); err != nil {
+			return err
+		}
+	}
+
+	t.statuses[id] = to
+	if !seen {
+		t.order = append(t.order, id)
+		t.evict()
+	}
+	return nil
+}
+
+// Status returns the current status of the payment.
+func (t *Tracker) Status(id string) (domain.Status, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.statuses[id]
+	return s, ok
+}
+
+func (t *Tracker) Len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.statuses)
+}
+
+func (t *Tracker) evict() {
+	for len(t.order) > t.capacity {
+		delete(t.statuses, t.order[0])
+		t.order[0] = ""
+		t.order = t.order[1:]
+	}
+}
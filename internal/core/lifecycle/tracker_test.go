@@ -0,0 +1,72 @@
+//go:build !integration
+
+package lifecycle
+
+import (
+	"testing"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrackerApply(t *testing.T) {
+	tr := NewTracker(10)
+
+	require.NoError(t, tr.Apply("1", domain.StatusCreated))
+	require.NoError(t, tr.Apply("1", domain.StatusAuthorized))
+
+	err := tr.Apply("1", domain.StatusCreated)
+	assert.ErrorIs(t, err, domain.ErrIllegalTransition)
+
+	require.NoError(t, tr.Apply("1", domain.StatusSettled))
+	require.NoError(t, tr.Apply("1", domain.StatusRefunded))
+	// a redelivered event keeps the status
+	require.NoError(t, tr.Apply("1", domain.StatusRefunded))
+	assert.ErrorIs(t, tr.Apply("1", domain.StatusSettled), domain.ErrIllegalTransition)
+
+	s, ok := tr.Status("1")
+	assert.True(t, ok)
+	assert.Equal(t, domain.StatusRefunded, s)
+
+	// the state of a payment may be lost by a restart or an eviction
+	require.NoError(t, tr.Apply("2", domain.StatusSettled))
+	s, _ = tr.Status("2")
+	assert.Equal(t, domain.StatusSettled, s)
+
+	assert.ErrorIs(t, tr.Apply("3", "lost"), domain.ErrIllegalTransition)
+	_, ok = tr.Status("3")
+	assert.False(t, ok)
+}
+
+func TestTrackerEvictsFirstSeen(t *testing.T) {
+	tr := NewTracker(2)
+	for _, id := range []string{"1", "2", "3"} {
+		require.NoError(t, tr.Apply(id, domain.StatusCreated))
+	}
+	require.NoError(t, tr.Apply("2", domain.StatusAuthorized))
+
+	assert.Equal(t, 2, tr.Len())
+	_, ok := tr.Status("1")
+	assert.False(t, ok)
+	s, _ := tr.Status("2")
+	assert.Equal(t, domain.StatusAuthorized, s)
+}
+
+func TestEventTypeStatus(t *testing.T) {
+	for _, s := range []domain.Status{
+		domain.StatusCreated, domain.StatusAuthorized,
+		domain.StatusSettled, domain.StatusRefunded,
+	} {
+		got, err := s.EventType().Status()
+		require.NoError(t, err)
+		assert.Equal(t, s, got)
+	}
+
+	s, err := domain.EventType("").Status()
+	require.NoError(t, err)
+	assert.Equal(t, domain.StatusCreated, s)
+
+	_, err = domain.EventType("payment.lost").Status()
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+)
+
+// CurrencyFilter keeps payments in the currencies.
+func CurrencyFilter(cs ...domain.Currency) FilterFunc {
+	return func(e domain.PaymentEnvelope) bool {
+		return slices.Contains(cs, e.Payment.Amount.Currency)
+	}
+}
+
+// StatusFilter keeps payments in the statuses.
+func StatusFilter(ss ...domain.Status) FilterFunc {
+	return func(e domain.PaymentEnvelope) bool {
+		return slices.Contains(ss, e.Payment.Status)
+	}
+}
+
+// MaskName keeps the first runes of the payer name
+// and replaces the rest with asterisks.
+func MaskName(keep int) MapperFunc {
+	return func(e domain.PaymentEnvelope) (domain.PaymentEnvelope, error) {
+		name := e.Payment.Name
+		n := utf8.RuneCountInString(name)
+		if n <= keep {
+			return e, nil
+		}
+		prefix := []rune(name)[:keep]
+		e.Payment.Name = string(prefix) + strings.Repeat("*", n-keep)
+		return e, nil
+	}
+}
+
+// DefaultDescription sets the description of payments without one.
+func DefaultDescription(text string) MapperFunc {
+	return func(e domain.PaymentEnvelope) (domain.PaymentEnvelope, error) {
+		if e.Payment.Description == "" {
+			e.Payment.Description = text
+		}
+		return e, nil
+	}
+}
+
+// CurrencyRouter routes payments by their currency code.
+func CurrencyRouter() RouterFunc {
+	return func(e domain.PaymentEnvelope) string {
+		return string(e.Payment.Amount.Currency)
+	}
+}
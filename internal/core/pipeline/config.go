@@ -0,0 +1,169 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+// Stage types of StageSpec.
+const (
+	TypeFilterCurrency     = "filter_currency"
+	TypeFilterStatus       = "filter_status"
+	TypeMaskName           = "mask_name"
+	TypeDefaultDescription = "default_description"
+	TypeRouteCurrency      = "route_currency"
+)
+
+// Names of the stages the service puts around the built stages,
+// they may not be used by the built stages.
+const (
+	StageReceive   = "receive"
+	StageValidate  = "validate"
+	StageStore     = "store"
+	StageAggregate = "aggregate"
+	StageFraud     = "fraud"
+)
+
+var reservedNames = []string{
+	StageReceive, StageValidate, StageStore, StageAggregate, StageFraud,
+}
+
+// StageSpec describes a built-in stage, only the fields of its type are
+// used: Currencies and Statuses by filters, Keep by mask_name, Text by
+// default_description, Routes of currency to sink name by route_currency.
+type StageSpec struct {
+	Name       string
+	Type       string
+	Currencies []string
+	Statuses   []string
+	Keep       int
+	Text       string
+	Routes     map[string]string
+}
+
+// StageError is a problem with the stage spec at Index.
+type StageError struct {
+	Index int
+	Name  string
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %d %q: %v", e.Index, e.Name, e.Err)
+}
+
+func (e *StageError) Unwrap() error { return e.Err }
+
+// Build creates the stages in order, route stages may
+// only use sinks from the sinks map. The stage name defaults to
+// its type, names must be unique and not reserved by the service.
+func Build(
+	specs []StageSpec, sinks map[string]port.PaymentsStorage,
+) ([]port.PipelineStage, error) {
+	const op = "pipeline.Build"
+
+	stages := make([]port.PipelineStage, 0, len(specs))
+	names := make(map[string]struct{}, len(specs))
+	for i, spec := range specs {
+		if spec.Name == "" {
+			spec.Name = spec.Type
+		}
+		if slices.Contains(reservedNames, spec.Name) {
+			err := &StageError{i, spec.Name, errors.New("name is reserved")}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if _, ok := names[spec.Name]; ok {
+			err := &StageError{i, spec.Name, errors.New("duplicate name, set a unique name")}
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		names[spec.Name] = struct{}{}
+
+		s, err := buildStage(spec, sinks)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, &StageError{i, spec.Name, err})
+		}
+		stages = append(stages, s)
+	}
+	return stages, nil
+}
+
+func buildStage(
+	spec StageSpec, sinks map[string]port.PaymentsStorage,
+) (port.PipelineStage, error) {
+	switch spec.Type {
+	case TypeFilterCurrency:
+		cs, err := parseCurrencies(spec.Currencies)
+		if err != nil {
+			return nil, err
+		}
+		return Filter(spec.Name, CurrencyFilter(cs...)), nil
+
+	case TypeFilterStatus:
+		if len(spec.Statuses) == 0 {
+			return nil, errors.New("statuses are empty")
+		}
+		ss := make([]domain.Status, 0, len(spec.Statuses))
+		for _, s := range spec.Statuses {
+			status := domain.Status(s)
+			if !status.Valid() {
+				return nil, fmt.Errorf("unknown status %q", s)
+			}
+			ss = append(ss, status)
+		}
+		return Filter(spec.Name, StatusFilter(ss...)), nil
+
+	case TypeMaskName:
+		if spec.Keep < 0 {
+			return nil, errors.New("keep is negative")
+		}
+		return Map(spec.Name, MaskName(spec.Keep)), nil
+
+	case TypeDefaultDescription:
+		if spec.Text == "" {
+			return nil, errors.New("text is empty")
+		}
+		return Map(spec.Name, DefaultDescription(spec.Text)), nil
+
+	case TypeRouteCurrency:
+		if len(spec.Routes) == 0 {
+			return nil, errors.New("routes are empty")
+		}
+		routes := make(map[string]port.PaymentsStorage, len(spec.Routes))
+		for c, sinkName := range spec.Routes {
+			// config loaders lower case map keys
+			c = strings.ToUpper(c)
+			if !domain.Currency(c).Valid() {
+				return nil, fmt.Errorf("unsupported currency %q", c)
+			}
+			sink, ok := sinks[sinkName]
+			if !ok {
+				return nil, fmt.Errorf("unknown sink %q", sinkName)
+			}
+			routes[c] = sink
+		}
+		return Route(spec.Name, CurrencyRouter(), routes), nil
+
+	default:
+		return nil, fmt.Errorf("unknown stage type %q", spec.Type)
+	}
+}
+
+func parseCurrencies(ss []string) ([]domain.Currency, error) {
+	if len(ss) == 0 {
+		return nil, errors.New("currencies are empty")
+	}
+	cs := make([]domain.Currency, 0, len(ss))
+	for _, s := range ss {
+		c := domain.Currency(s)
+		if !c.Valid() {
+			return nil, fmt.Errorf("unsupported currency %q", s)
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
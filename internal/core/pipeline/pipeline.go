@@ -0,0 +1,69 @@
+// Package pipeline runs received payments through ordered stages.
+package pipeline
+
+import (
+	"expvar"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+// metrics counts payments per stage, the keys are "<stage>.in",
+// "<stage>.out", "<stage>.errors" and "<stage>.duration_us".
+var metrics = expvar.NewMap("payment_pipeline")
+
+type Pipeline struct {
+	stages []port.PipelineStage
+}
+
+// New panics on duplicate stage names, the names key the metrics.
+func New(stages ...port.PipelineStage) *Pipeline {
+	const op = "pipeline.New"
+
+	seen := make(map[string]bool, len(stages))
+	for _, s := range stages {
+		if seen[s.Name()] {
+			panic(fmt.Errorf("%s: duplicate stage %q", op, s.Name())) // develop mistake
+		}
+		seen[s.Name()] = true
+	}
+	return &Pipeline{stages}
+}
+
+func (p *Pipeline) Stages() []string {
+	names := make([]string, 0, len(p.stages))
+	for _, s := range p.stages {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// Run passes the payments through all stages and returns the ones left
+// after the last stage. A failed stage is logged and the pipeline goes
+// on with the payments the stage returned.
+func (p *Pipeline) Run(es []domain.PaymentEnvelope) []domain.PaymentEnvelope {
+	const op = "Pipeline.Run"
+
+	for _, s := range p.stages {
+		if len(es) == 0 {
+			break
+		}
+		name := s.Name()
+		metrics.Add(name+".in", int64(len(es)))
+
+		start := time.Now()
+		out, err := s.Process(es)
+		metrics.Add(name+".duration_us", time.Since(start).Microseconds())
+
+		if err != nil {
+			metrics.Add(name+".errors", 1)
+			slog.Error("stage failed", "op", op, "stage", name, "err", err)
+		}
+		metrics.Add(name+".out", int64(len(out)))
+		es = out
+	}
+	return es
+}
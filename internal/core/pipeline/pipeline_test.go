@@ -0,0 +1,159 @@
+//go:build !integration
+
+package pipeline
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSink struct {
+	saved []domain.PaymentEnvelope
+	err   error
+}
+
+func (s *stubSink) Save(es []domain.PaymentEnvelope) error {
+	s.saved = append(s.saved, es...)
+	return s.err
+}
+
+func envelope(name string, c domain.Currency, st domain.Status) domain.PaymentEnvelope {
+	return domain.PaymentEnvelope{Payment: domain.Payment{
+		ID: name, Name: name, Amount: domain.NewMoney(100, c), Status: st,
+	}}
+}
+
+func names(es []domain.PaymentEnvelope) []string {
+	var ns []string
+	for _, e := range es {
+		ns = append(ns, e.Payment.Name)
+	}
+	return ns
+}
+
+func TestFilter(t *testing.T) {
+	s := Filter("f", CurrencyFilter(domain.CurrencyRUB))
+	out, err := s.Process([]domain.PaymentEnvelope{
+		envelope("A", domain.CurrencyRUB, domain.StatusCreated),
+		envelope("B", domain.CurrencyUSD, domain.StatusCreated),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A"}, names(out))
+
+	s = Filter("f", StatusFilter(domain.StatusSettled))
+	out, _ = s.Process([]domain.PaymentEnvelope{
+		envelope("A", domain.CurrencyRUB, domain.StatusCreated),
+	})
+	assert.Empty(t, out)
+}
+
+func TestMap(t *testing.T) {
+	out, err := Map("m", MaskName(1)).Process([]domain.PaymentEnvelope{
+		envelope("ABCDE", domain.CurrencyRUB, domain.StatusCreated),
+		envelope("Я", domain.CurrencyRUB, domain.StatusCreated),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A****", "Я"}, names(out))
+
+	out, _ = Map("m", DefaultDescription("x")).Process([]domain.PaymentEnvelope{
+		envelope("A", domain.CurrencyRUB, domain.StatusCreated),
+	})
+	assert.Equal(t, "x", out[0].Payment.Description)
+
+	failing := MapperFunc(func(e domain.PaymentEnvelope) (domain.PaymentEnvelope, error) {
+		if e.Payment.Name == "B" {
+			return e, errors.New("boom")
+		}
+		return e, nil
+	})
+	out, err = Map("m", failing).Process([]domain.PaymentEnvelope{
+		envelope("A", domain.CurrencyRUB, domain.StatusCreated),
+		envelope("B", domain.CurrencyRUB, domain.StatusCreated),
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"A"}, names(out))
+}
+
+func TestRoute(t *testing.T) {
+	usd := &stubSink{}
+	s := Route("r", CurrencyRouter(), map[string]port.PaymentsStorage{"USD": usd})
+	out, err := s.Process([]domain.PaymentEnvelope{
+		envelope("A", domain.CurrencyRUB, domain.StatusCreated),
+		envelope("B", domain.CurrencyUSD, domain.StatusCreated),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A"}, names(out))
+	assert.Equal(t, []string{"B"}, names(usd.saved))
+}
+
+func TestSink(t *testing.T) {
+	sink := &stubSink{err: errors.New("boom")}
+	in := []domain.PaymentEnvelope{envelope("A", domain.CurrencyRUB, domain.StatusCreated)}
+	out, err := Sink("s", sink).Process(in)
+	assert.Error(t, err)
+	assert.Equal(t, in, out, "payments pass on after a failed save")
+	assert.Equal(t, in, sink.saved)
+}
+
+func TestRunMetrics(t *testing.T) {
+	sink := &stubSink{err: errors.New("boom")}
+	p := New(
+		Filter("test_filter", CurrencyFilter(domain.CurrencyRUB)),
+		Sink("test_sink", sink),
+	)
+	out := p.Run([]domain.PaymentEnvelope{
+		envelope("A", domain.CurrencyRUB, domain.StatusCreated),
+		envelope("B", domain.CurrencyUSD, domain.StatusCreated),
+	})
+	assert.Equal(t, []string{"A"}, names(out))
+
+	assert.Equal(t, "2", metrics.Get("test_filter.in").String())
+	assert.Equal(t, "1", metrics.Get("test_filter.out").String())
+	assert.Equal(t, "1", metrics.Get("test_sink.errors").String())
+	assert.Equal(t, []string{"test_filter", "test_sink"}, p.Stages())
+
+	assert.Panics(t, func() { New(Sink("x", sink), Sink("x", sink)) })
+}
+
+func TestBuild(t *testing.T) {
+	sinks := map[string]port.PaymentsStorage{"discard": Discard}
+	stages, err := Build([]StageSpec{
+		{Type: TypeFilterCurrency, Currencies: []string{"RUB"}},
+		{Name: "mask", Type: TypeMaskName, Keep: 2},
+		{Type: TypeFilterStatus, Statuses: []string{"created"}},
+		{Type: TypeDefaultDescription, Text: "x"},
+		{Type: TypeRouteCurrency, Routes: map[string]string{"rub": "discard"}},
+	}, sinks)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"filter_currency", "mask", "filter_status",
+		"default_description", "route_currency",
+	}, New(stages...).Stages())
+
+	for _, spec := range []StageSpec{
+		{Type: "unknown"},
+		{Type: TypeFilterCurrency},
+		{Type: TypeFilterCurrency, Currencies: []string{"ZZZ"}},
+		{Type: TypeFilterStatus, Statuses: []string{"lost"}},
+		{Type: TypeMaskName, Keep: -1},
+		{Type: TypeDefaultDescription},
+		{Type: TypeRouteCurrency, Routes: map[string]string{"RUB": "kafka"}},
+	} {
+		_, err := Build([]StageSpec{spec}, sinks)
+		assert.Error(t, err, "%+v", spec)
+	}
+
+	for _, specs := range [][]StageSpec{
+		{{Type: TypeMaskName}, {Type: TypeMaskName, Keep: 1}},
+		{{Name: "mask", Type: TypeMaskName}, {Name: "mask", Type: TypeDefaultDescription, Text: "x"}},
+		{{Name: StageStore, Type: TypeMaskName}},
+	} {
+		_, err := Build(specs, sinks)
+		assert.Error(t, err, "%+v", specs)
+	}
+}
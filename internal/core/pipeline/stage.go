@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+type FilterFunc func(domain.PaymentEnvelope) bool
+
+func (f FilterFunc) Keep(e domain.PaymentEnvelope) bool { return f(e) }
+
+type MapperFunc func(domain.PaymentEnvelope) (domain.PaymentEnvelope, error)
+
+func (f MapperFunc) Map(e domain.PaymentEnvelope) (domain.PaymentEnvelope, error) {
+	return f(e)
+}
+
+type RouterFunc func(domain.PaymentEnvelope) string
+
+func (f RouterFunc) Route(e domain.PaymentEnvelope) string { return f(e) }
+
+type funcStage struct {
+	name string
+	fn   func([]domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error)
+}
+
+func (s funcStage) Name() string { return s.name }
+
+func (s funcStage) Process(es []domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error) {
+	return s.fn(es)
+}
+
+// Func adapts a function to a stage.
+func Func(
+	name string, fn func([]domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error),
+) port.PipelineStage {
+	return funcStage{name, fn}
+}
+
+// Filter drops the payments the filter does not keep.
+func Filter(name string, f port.PaymentFilter) port.PipelineStage {
+	return Func(name, func(es []domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error) {
+		out := make([]domain.PaymentEnvelope, 0, len(es))
+		for _, e := range es {
+			if f.Keep(e) {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	})
+}
+
+// Map changes payments one by one, a payment that fails to map is dropped.
+func Map(name string, m port.PaymentMapper) port.PipelineStage {
+	return Func(name, func(es []domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error) {
+		out := make([]domain.PaymentEnvelope, 0, len(es))
+		var errs []error
+		for _, e := range es {
+			mapped, err := m.Map(e)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("payment %s: %w", e.Payment.ID, err))
+				continue
+			}
+			out = append(out, mapped)
+		}
+		return out, errors.Join(errs...)
+	})
+}
+
+// Route saves routed payments to the sink of their route and takes them
+// out of the pipeline. Payments of unknown or empty routes go on.
+func Route(
+	name string, r port.PaymentRouter, routes map[string]port.PaymentsStorage,
+) port.PipelineStage {
+	return Func(name, func(es []domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error) {
+		out := make([]domain.PaymentEnvelope, 0, len(es))
+		routed := make(map[string][]domain.PaymentEnvelope)
+		for _, e := range es {
+			route := r.Route(e)
+			if _, ok := routes[route]; !ok {
+				out = append(out, e)
+				continue
+			}
+			routed[route] = append(routed[route], e)
+		}
+
+		var errs []error
+		for route, res := range routed {
+			if err := routes[route].Save(res); err != nil {
+				errs = append(errs, fmt.Errorf("route %q: %w", route, err))
+			}
+		}
+		return out, errors.Join(errs...)
+	})
+}
+
+// Sink saves the payments and passes them on.
+func Sink(name string, s port.PaymentsStorage) port.PipelineStage {
+	return Func(name, func(es []domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error) {
+		return es, s.Save(es)
+	})
+}
+
+// Discard is a sink that drops payments.
+var Discard port.PaymentsStorage = discard{}
+
+type discard struct{}
+
+func (discard) Save([]domain.PaymentEnvelope) error { return nil }
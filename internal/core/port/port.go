@@ -15,9 +15,64 @@ type PaymentProducer interface {
 }
 
 type PaymentReceiver interface {
-	ReceivePayments([]domain.Payment)
+	ReceivePayments([]domain.PaymentEnvelope)
 }
 
 type PaymentsStorage interface {
-	Save([]domain.Payment) error
+	Save([]domain.PaymentEnvelope) error
+}
+
+type PaymentValidator interface {
+	// Validate returns nil when the payment is valid.
+	Validate(domain.Payment) []domain.RejectionReason
+}
+
+type RejectedPaymentsStorage interface {
+	SaveRejected([]domain.RejectedPayment) error
+}
+
+type PaymentStateTracker interface {
+	// Apply moves the payment to the status
+	// or fails on an illegal transition.
+	Apply(id string, to domain.Status) error
+}
+
+type PaymentAggregator interface {
+	// Add returns the windows closed by the payments.
+	Add([]domain.Payment) []domain.WindowAggregate
+	// Flush returns all open windows.
+	Flush() []domain.WindowAggregate
+}
+
+type AggregatesSink interface {
+	SaveAggregates([]domain.WindowAggregate) error
+}
+
+type FraudDetector interface {
+	Inspect(domain.Payment) []domain.FraudAlert
+}
+
+type AlertsSink interface {
+	PublishAlerts([]domain.FraudAlert) error
+}
+
+// PipelineStage processes received payments in order, it returns the
+// payments passed to the next stage even when it fails.
+type PipelineStage interface {
+	Name() string
+	Process([]domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error)
+}
+
+type PaymentFilter interface {
+	Keep(domain.PaymentEnvelope) bool
+}
+
+type PaymentMapper interface {
+	Map(domain.PaymentEnvelope) (domain.PaymentEnvelope, error)
+}
+
+type PaymentRouter interface {
+	// Route returns the route name, the empty name
+	// keeps the payment in the pipeline.
+	Route(domain.PaymentEnvelope) string
 }
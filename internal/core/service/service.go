@@ -2,31 +2,204 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/pipeline"
 	"github.com/niksmo/cloud-integration/internal/core/port"
 )
 
 var _ port.PaymentSender = (*Service)(nil)
 var _ port.PaymentReceiver = (*Service)(nil)
 
+type Opt func(*options) error
+
+// ValidatorOpt rejects payments that fail validation,
+// without it all payments are accepted.
+func ValidatorOpt(v port.PaymentValidator) Opt {
+	return func(o *options) error {
+		if v == nil {
+			return errors.New("validator is nil")
+		}
+		o.validator = v
+		return nil
+	}
+}
+
+// RejectSinkOpt saves rejected payments,
+// without it they are only logged.
+func RejectSinkOpt(s port.RejectedPaymentsStorage) Opt {
+	return func(o *options) error {
+		if s == nil {
+			return errors.New("reject sink is nil")
+		}
+		o.rejectSink = s
+		return nil
+	}
+}
+
+// StateTrackerOpt rejects received payments that make
+// an illegal lifecycle transition.
+func StateTrackerOpt(t port.PaymentStateTracker) Opt {
+	return func(o *options) error {
+		if t == nil {
+			return errors.New("state tracker is nil")
+		}
+		o.tracker = t
+		return nil
+	}
+}
+
+// AggregatorOpt aggregates received payments in windows,
+// closed windows are saved to every sink.
+func AggregatorOpt(a port.PaymentAggregator, sinks ...port.AggregatesSink) Opt {
+	return func(o *options) error {
+		if a == nil {
+			return errors.New("aggregator is nil")
+		}
+		if len(sinks) == 0 || slices.Contains(sinks, nil) {
+			return errors.New("aggregates sinks are not set")
+		}
+		o.aggregator = a
+		o.aggregatesSinks = sinks
+		return nil
+	}
+}
+
+// FraudDetectorOpt inspects received payments,
+// alerts are published to the sink.
+func FraudDetectorOpt(d port.FraudDetector, sink port.AlertsSink) Opt {
+	return func(o *options) error {
+		if d == nil || sink == nil {
+			return errors.New("fraud detector or alerts sink is nil")
+		}
+		o.fraudDetector = d
+		o.alertsSink = sink
+		return nil
+	}
+}
+
+// StagesOpt adds stages that run before the payments are saved, e.g.
+// enrichment or masking. They run after aggregation and fraud detection,
+// so they shape the stored payments only. The names receive, validate,
+// store, aggregate and fraud are taken by the service.
+func StagesOpt(stages ...port.PipelineStage) Opt {
+	return func(o *options) error {
+		if slices.Contains(stages, nil) {
+			return errors.New("pipeline stage is nil")
+		}
+		o.stages = append(o.stages, stages...)
+		return nil
+	}
+}
+
+type options struct {
+	validator       port.PaymentValidator
+	rejectSink      port.RejectedPaymentsStorage
+	tracker         port.PaymentStateTracker
+	aggregator      port.PaymentAggregator
+	aggregatesSinks []port.AggregatesSink
+	fraudDetector   port.FraudDetector
+	alertsSink      port.AlertsSink
+	stages          []port.PipelineStage
+}
+
 type Service struct {
-	producer port.PaymentProducer
-	storage  port.PaymentsStorage
+	producer        port.PaymentProducer
+	storage         port.PaymentsStorage
+	validator       port.PaymentValidator
+	rejectSink      port.RejectedPaymentsStorage
+	tracker         port.PaymentStateTracker
+	aggregator      port.PaymentAggregator
+	aggregatesSinks []port.AggregatesSink
+	fraudDetector   port.FraudDetector
+	alertsSink      port.AlertsSink
+	pipeline        *pipeline.Pipeline
 }
 
-func New(p port.PaymentProducer, s port.PaymentsStorage) Service {
-	return Service{p, s}
+// ErrNoProducer is returned by SendPayment of a service without a producer.
+var ErrNoProducer = errors.New("payments producer is not set")
+
+// New returns the service. A consumer only process passes a nil p and
+// SendPayment fails with ErrNoProducer, a producer only one passes a
+// nil s and received payments are not saved.
+func New(p port.PaymentProducer, s port.PaymentsStorage, opts ...Opt) Service {
+	const op = "service.New"
+
+	var options options
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			panic(fmt.Errorf("%s: %w", op, err)) // develop mistake
+		}
+	}
+	svc := Service{
+		producer:        p,
+		storage:         s,
+		validator:       options.validator,
+		rejectSink:      options.rejectSink,
+		tracker:         options.tracker,
+		aggregator:      options.aggregator,
+		aggregatesSinks: options.aggregatesSinks,
+		fraudDetector:   options.fraudDetector,
+		alertsSink:      options.alertsSink,
+	}
+	svc.pipeline = svc.newPipeline(options.stages)
+	return svc
 }
 
+// newPipeline puts the stages added with StagesOpt between the service
+// own stages. Aggregation and fraud detection see every valid payment
+// as received, the added stages may mask, drop or route them before
+// the store stage.
+func (s Service) newPipeline(stages []port.PipelineStage) *pipeline.Pipeline {
+	all := []port.PipelineStage{
+		pipeline.Func(pipeline.StageReceive, s.logReceived),
+		pipeline.Func(pipeline.StageValidate, s.validatePayments),
+	}
+	if s.aggregator != nil {
+		all = append(all, pipeline.Func(pipeline.StageAggregate, s.aggregate))
+	}
+	if s.fraudDetector != nil {
+		all = append(all, pipeline.Func(pipeline.StageFraud, s.detectFraud))
+	}
+	all = append(all, stages...)
+	storage := s.storage
+	if storage == nil {
+		storage = pipeline.Discard
+	}
+	all = append(all, pipeline.Sink(pipeline.StageStore, storage))
+	return pipeline.New(all...)
+}
+
+// SendPayment returns *domain.RejectionError
+// when the payment fails validation.
 func (s Service) SendPayment(ctx context.Context, p domain.Payment) error {
 	const op = "Service.SendPayment"
 	if err := ctx.Err(); err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
 
+	if reasons := s.validate(p); len(reasons) != 0 {
+		s.reject([]domain.RejectedPayment{
+			{
+				Envelope:   domain.PaymentEnvelope{Payment: p},
+				Reasons:    reasons,
+				RejectedAt: time.Now(),
+			},
+		})
+		return fmt.Errorf(
+			"%s: %w", op, &domain.RejectionError{PaymentID: p.ID, Reasons: reasons},
+		)
+	}
+
+	if s.producer == nil {
+		return fmt.Errorf("%s: %w", op, ErrNoProducer)
+	}
+
 	err := s.producer.ProducePayment(ctx, p)
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
@@ -34,20 +207,160 @@ func (s Service) SendPayment(ctx context.Context, p domain.Payment) error {
 	return nil
 }
 
-func (s Service) ReceivePayments(ps []domain.Payment) {
+func (s Service) ReceivePayments(es []domain.PaymentEnvelope) {
+	s.pipeline.Run(es)
+}
+
+func (s Service) logReceived(
+	es []domain.PaymentEnvelope,
+) ([]domain.PaymentEnvelope, error) {
 	const op = "Service.ReceivePayment"
 	log := slog.With("op", op)
-	for _, p := range ps {
-		log.Info("receive payment", "payment", p)
+	for _, e := range es {
+		log.Info(
+			"receive payment",
+			"payment", e.Payment, "metadata", e.Metadata,
+		)
+	}
+	return es, nil
+}
+
+// validatePayments passes valid payments on and rejects the others.
+func (s Service) validatePayments(
+	es []domain.PaymentEnvelope,
+) ([]domain.PaymentEnvelope, error) {
+	valid := make([]domain.PaymentEnvelope, 0, len(es))
+	var rejected []domain.RejectedPayment
+	for _, e := range es {
+		reasons := s.validate(e.Payment)
+		if len(reasons) == 0 {
+			reasons = s.track(e.Payment)
+		}
+		if len(reasons) != 0 {
+			rejected = append(rejected, domain.RejectedPayment{
+				Envelope:   e,
+				Reasons:    reasons,
+				RejectedAt: time.Now(),
+			})
+			continue
+		}
+		valid = append(valid, e)
+	}
+
+	if len(rejected) != 0 {
+		s.reject(rejected)
+	}
+	return valid, nil
+}
+
+// Close saves the windows that are still open.
+func (s Service) Close() {
+	const op = "Service.Close"
+
+	if s.aggregator == nil {
+		return
+	}
+	if err := s.saveAggregates(s.aggregator.Flush()); err != nil {
+		slog.Error("failed to save aggregates", "op", op, "err", err)
+	}
+}
+
+func (s Service) aggregate(
+	es []domain.PaymentEnvelope,
+) ([]domain.PaymentEnvelope, error) {
+	return es, s.saveAggregates(s.aggregator.Add(created(es)))
+}
+
+// detectFraud flags payments but does not hold them back,
+// they are saved by the later stages.
+func (s Service) detectFraud(
+	es []domain.PaymentEnvelope,
+) ([]domain.PaymentEnvelope, error) {
+	const op = "Service.detectFraud"
+	log := slog.With("op", op)
+
+	var alerts []domain.FraudAlert
+	for _, p := range created(es) {
+		alerts = append(alerts, s.fraudDetector.Inspect(p)...)
+	}
+	if len(alerts) == 0 {
+		return es, nil
+	}
+	for _, a := range alerts {
+		log.Warn(
+			"suspicious payment",
+			"rule", a.Rule, "paymentID", a.PaymentID, "evidence", a.Evidence,
+		)
+	}
+	if err := s.alertsSink.PublishAlerts(alerts); err != nil {
+		return es, fmt.Errorf("%s: %w", op, err)
 	}
+	return es, nil
+}
+
+// created returns payments of created events only, so the
+// later lifecycle events of a payment are not counted twice.
+func created(es []domain.PaymentEnvelope) []domain.Payment {
+	ps := make([]domain.Payment, 0, len(es))
+	for _, e := range es {
+		if e.Payment.Status == domain.StatusCreated {
+			ps = append(ps, e.Payment)
+		}
+	}
+	return ps
+}
+
+func (s Service) saveAggregates(ws []domain.WindowAggregate) error {
+	const op = "Service.saveAggregates"
 
-	s.savePayments(ps)
+	if len(ws) == 0 {
+		return nil
+	}
+	var errs []error
+	for _, sink := range s.aggregatesSinks {
+		if err := sink.SaveAggregates(ws); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", op, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s Service) validate(p domain.Payment) []domain.RejectionReason {
+	if s.validator == nil {
+		return nil
+	}
+	return s.validator.Validate(p)
 }
 
-func (s Service) savePayments(ps []domain.Payment) {
-	const op = "Service.storePayments"
+// track applies the payment status, only valid
+// payments are tracked so they never poison the state.
+func (s Service) track(p domain.Payment) []domain.RejectionReason {
+	if s.tracker == nil {
+		return nil
+	}
+	if err := s.tracker.Apply(p.ID, p.Status); err != nil {
+		return []domain.RejectionReason{
+			{Rule: "lifecycle_transition", Field: "status", Message: err.Error()},
+		}
+	}
+	return nil
+}
+
+func (s Service) reject(rs []domain.RejectedPayment) {
+	const op = "Service.reject"
 	log := slog.With("op", op)
-	if err := s.storage.Save(ps); err != nil {
-		log.Error("failed to save payments", "err", err)
+
+	for _, r := range rs {
+		log.Warn(
+			"payment rejected",
+			"paymentID", r.Envelope.Payment.ID, "reasons", r.Reasons,
+		)
+	}
+
+	if s.rejectSink == nil {
+		return
+	}
+	if err := s.rejectSink.SaveRejected(rs); err != nil {
+		log.Error("failed to save rejected payments", "err", err)
 	}
 }
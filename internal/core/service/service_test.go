@@ -0,0 +1,196 @@
+//go:build !integration
+
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/lifecycle"
+	"github.com/niksmo/cloud-integration/internal/core/pipeline"
+	"github.com/niksmo/cloud-integration/internal/core/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubProducer struct{ produced []domain.Payment }
+
+func (p *stubProducer) ProducePayment(_ context.Context, pm domain.Payment) error {
+	p.produced = append(p.produced, pm)
+	return nil
+}
+
+type stubStorage struct {
+	saved    []domain.PaymentEnvelope
+	rejected []domain.RejectedPayment
+}
+
+func (s *stubStorage) Save(es []domain.PaymentEnvelope) error {
+	s.saved = append(s.saved, es...)
+	return nil
+}
+
+func (s *stubStorage) SaveRejected(rs []domain.RejectedPayment) error {
+	s.rejected = append(s.rejected, rs...)
+	return nil
+}
+
+func newTestService() (Service, *stubProducer, *stubStorage) {
+	p, st := &stubProducer{}, &stubStorage{}
+	v := validation.New(validation.NameRequired(), validation.AmountPositive())
+	return New(p, st, ValidatorOpt(v), RejectSinkOpt(st)), p, st
+}
+
+func TestSendPaymentRejected(t *testing.T) {
+	s, p, st := newTestService()
+
+	err := s.SendPayment(context.Background(), domain.NewPayment("", domain.Money{}))
+	require.ErrorIs(t, err, domain.ErrPaymentRejected)
+
+	var rejErr *domain.RejectionError
+	require.ErrorAs(t, err, &rejErr)
+	assert.Len(t, rejErr.Reasons, 2)
+	assert.Empty(t, p.produced)
+	assert.Len(t, st.rejected, 1)
+
+	ok := domain.NewPayment("A", domain.NewMoney(1, domain.CurrencyRUB))
+	require.NoError(t, s.SendPayment(context.Background(), ok))
+	assert.Len(t, p.produced, 1)
+}
+
+func TestSendPaymentWithoutProducer(t *testing.T) {
+	s := New(nil, &stubStorage{})
+	ok := domain.NewPayment("A", domain.NewMoney(1, domain.CurrencyRUB))
+	assert.ErrorIs(t, s.SendPayment(context.Background(), ok), ErrNoProducer)
+}
+
+func TestReceivePaymentsSplitsRejected(t *testing.T) {
+	s, _, st := newTestService()
+
+	valid := domain.PaymentEnvelope{Payment: domain.Payment{
+		ID: uuid.NewString(), Name: "A", Amount: domain.NewMoney(1, domain.CurrencyRUB),
+	}}
+	invalid := domain.PaymentEnvelope{Payment: domain.Payment{
+		ID: uuid.NewString(), Name: "B", Amount: domain.NewMoney(-1, domain.CurrencyRUB),
+	}}
+	s.ReceivePayments([]domain.PaymentEnvelope{valid, invalid})
+
+	assert.Equal(t, []domain.PaymentEnvelope{valid}, st.saved)
+	require.Len(t, st.rejected, 1)
+	assert.Equal(t, invalid, st.rejected[0].Envelope)
+	assert.Equal(t, "amount_positive", st.rejected[0].Reasons[0].Rule)
+}
+
+func TestReceivePaymentsRejectsIllegalTransition(t *testing.T) {
+	st := &stubStorage{}
+	s := New(
+		&stubProducer{}, st,
+		RejectSinkOpt(st),
+		StateTrackerOpt(lifecycle.NewTracker(10)),
+	)
+
+	p := domain.NewPayment("A", domain.NewMoney(1, domain.CurrencyRUB))
+	settled := p
+	settled.Status = domain.StatusSettled
+
+	s.ReceivePayments([]domain.PaymentEnvelope{{Payment: p}, {Payment: settled}})
+
+	assert.Equal(t, []domain.PaymentEnvelope{{Payment: p}}, st.saved)
+	require.Len(t, st.rejected, 1)
+	assert.Equal(t, "lifecycle_transition", st.rejected[0].Reasons[0].Rule)
+}
+
+func TestReceivePaymentsAcceptsRedelivery(t *testing.T) {
+	st := &stubStorage{}
+	s := New(
+		&stubProducer{}, st,
+		RejectSinkOpt(st),
+		StateTrackerOpt(lifecycle.NewTracker(10)),
+	)
+
+	p := domain.NewPayment("A", domain.NewMoney(1, domain.CurrencyRUB))
+	authorized := p
+	authorized.Status = domain.StatusAuthorized
+
+	s.ReceivePayments([]domain.PaymentEnvelope{{Payment: p}, {Payment: authorized}})
+	s.ReceivePayments([]domain.PaymentEnvelope{{Payment: authorized}})
+
+	assert.Equal(t, []domain.PaymentEnvelope{
+		{Payment: p}, {Payment: authorized}, {Payment: authorized},
+	}, st.saved)
+	assert.Empty(t, st.rejected)
+}
+
+func TestReceivePaymentsAcceptsUnknownID(t *testing.T) {
+	st := &stubStorage{}
+	s := New(
+		&stubProducer{}, st,
+		RejectSinkOpt(st),
+		StateTrackerOpt(lifecycle.NewTracker(10)),
+	)
+
+	// the created event was received before a restart
+	settled := domain.NewPayment("A", domain.NewMoney(1, domain.CurrencyRUB))
+	settled.Status = domain.StatusSettled
+	refunded := settled
+	refunded.Status = domain.StatusRefunded
+
+	s.ReceivePayments([]domain.PaymentEnvelope{{Payment: settled}, {Payment: refunded}})
+
+	assert.Equal(t, []domain.PaymentEnvelope{{Payment: settled}, {Payment: refunded}}, st.saved)
+	assert.Empty(t, st.rejected)
+}
+
+type stubAggregator struct{ added []domain.Payment }
+
+func (a *stubAggregator) Add(ps []domain.Payment) []domain.WindowAggregate {
+	a.added = append(a.added, ps...)
+	return nil
+}
+
+func (a *stubAggregator) Flush() []domain.WindowAggregate { return nil }
+
+type stubDetector struct{ inspected []domain.Payment }
+
+func (d *stubDetector) Inspect(p domain.Payment) []domain.FraudAlert {
+	d.inspected = append(d.inspected, p)
+	return nil
+}
+
+type stubSinks struct{}
+
+func (stubSinks) SaveAggregates([]domain.WindowAggregate) error { return nil }
+func (stubSinks) PublishAlerts([]domain.FraudAlert) error       { return nil }
+
+func TestStagesShapeStoredPaymentsOnly(t *testing.T) {
+	st, agg, det := &stubStorage{}, &stubAggregator{}, &stubDetector{}
+	mask := pipeline.Func("mask", func(es []domain.PaymentEnvelope) ([]domain.PaymentEnvelope, error) {
+		out := make([]domain.PaymentEnvelope, 0, len(es))
+		for _, e := range es {
+			if e.Payment.Amount.Currency == domain.CurrencyCNY {
+				continue
+			}
+			e.Payment.Name = strings.Repeat("*", len(e.Payment.Name))
+			out = append(out, e)
+		}
+		return out, nil
+	})
+	s := New(
+		&stubProducer{}, st,
+		AggregatorOpt(agg, stubSinks{}),
+		FraudDetectorOpt(det, stubSinks{}),
+		StagesOpt(mask),
+	)
+
+	rub := domain.NewPayment("ALICE", domain.NewMoney(1, domain.CurrencyRUB))
+	cny := domain.NewPayment("BOB", domain.NewMoney(1, domain.CurrencyCNY))
+	s.ReceivePayments([]domain.PaymentEnvelope{{Payment: rub}, {Payment: cny}})
+
+	assert.Equal(t, []domain.Payment{rub, cny}, agg.added)
+	assert.Equal(t, []domain.Payment{rub, cny}, det.inspected)
+	require.Len(t, st.saved, 1)
+	assert.Equal(t, "*****", st.saved[0].Payment.Name)
+}
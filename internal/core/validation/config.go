@@ -0,0 +1,116 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+)
+
+// Config enables the optional rules, zero values are not checked.
+// Amounts are decimals in major units, e.g. "0.01".
+type Config struct {
+	// MinAmount and MaxAmount apply to the currencies
+	// that have no AmountLimits of their own.
+	MinAmount string
+	MaxAmount string
+	// AmountLimits are keyed by currency code, case insensitive.
+	AmountLimits      map[string]Limits
+	AllowedCurrencies []string
+	NamePattern       string
+	NameMaxLength     int
+}
+
+// FromConfig builds a validator of the rules every payment has to
+// pass plus the configured ones. Empty AllowedCurrencies allows all
+// supported currencies.
+func FromConfig(cfg Config) (*Validator, error) {
+	const op = "validation.FromConfig"
+
+	rules := []Rule{
+		IDFormat(), NameRequired(), AmountPositive(), StatusValid(),
+	}
+
+	if cfg.NameMaxLength > 0 {
+		rules = append(rules, NameMaxLength(cfg.NameMaxLength))
+	}
+
+	if cfg.NamePattern != "" {
+		re, err := regexp.Compile(cfg.NamePattern)
+		if err != nil {
+			return nil, fmt.Errorf("%s: name pattern: %w", op, err)
+		}
+		rules = append(rules, NamePattern(re))
+	}
+
+	def, err := Limits{cfg.MinAmount, cfg.MaxAmount}.parse()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	byCurrency := make(map[domain.Currency]Range, len(cfg.AmountLimits))
+	for code, l := range cfg.AmountLimits {
+		// config loaders lower case map keys
+		c := domain.Currency(strings.ToUpper(code))
+		if !c.Valid() {
+			return nil, fmt.Errorf("%s: amount limits: unsupported currency %q", op, code)
+		}
+		r, err := l.parse()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", op, c, err)
+		}
+		byCurrency[c] = r
+	}
+	if def.Min != nil || def.Max != nil || len(byCurrency) != 0 {
+		rules = append(rules, AmountRange(def, byCurrency))
+	}
+
+	currencies := domain.Currencies()
+	if len(cfg.AllowedCurrencies) != 0 {
+		currencies = currencies[:0]
+		for _, s := range cfg.AllowedCurrencies {
+			c := domain.Currency(s)
+			if !c.Valid() {
+				return nil, fmt.Errorf("%s: unsupported currency %q", op, s)
+			}
+			currencies = append(currencies, c)
+		}
+	}
+	rules = append(rules, CurrencyAllowed(currencies...))
+
+	return New(rules...), nil
+}
+
+// Limits is a range of decimal amounts, an empty bound is not checked.
+type Limits struct {
+	Min string
+	Max string
+}
+
+func (l Limits) parse() (Range, error) {
+	lo, err := parseLimit(l.Min)
+	if err != nil {
+		return Range{}, fmt.Errorf("min amount: %w", err)
+	}
+	hi, err := parseLimit(l.Max)
+	if err != nil {
+		return Range{}, fmt.Errorf("max amount: %w", err)
+	}
+	if lo != nil && hi != nil && lo.Cmp(hi) > 0 {
+		return Range{}, errors.New("min amount is greater than max amount")
+	}
+	return Range{lo, hi}, nil
+}
+
+func parseLimit(s string) (*big.Rat, error) {
+	if s == "" {
+		return nil, nil
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
@@ -0,0 +1,113 @@
+// Package validation checks payments against composable rules
+// and explains every rejection with a structured reason.
+package validation
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+)
+
+// Rule checks one payment field, Check returns
+// an empty message when the payment passes.
+type Rule struct {
+	Name  string
+	Field string
+	Check func(domain.Payment) string
+}
+
+func (r Rule) reason(msg string) domain.RejectionReason {
+	return domain.RejectionReason{Rule: r.Name, Field: r.Field, Message: msg}
+}
+
+func IDFormat() Rule {
+	return Rule{"id_format", "id", func(p domain.Payment) string {
+		if _, err := uuid.Parse(p.ID); err != nil {
+			return fmt.Sprintf("%q is not a UUID", p.ID)
+		}
+		return ""
+	}}
+}
+
+func NameRequired() Rule {
+	return Rule{"name_required", "name", func(p domain.Payment) string {
+		if p.Name == "" {
+			return "name is empty"
+		}
+		return ""
+	}}
+}
+
+func NameMaxLength(n int) Rule {
+	return Rule{"name_max_length", "name", func(p domain.Payment) string {
+		if l := utf8.RuneCountInString(p.Name); l > n {
+			return fmt.Sprintf("name length %d exceeds %d", l, n)
+		}
+		return ""
+	}}
+}
+
+func NamePattern(re *regexp.Regexp) Rule {
+	return Rule{"name_pattern", "name", func(p domain.Payment) string {
+		if !re.MatchString(p.Name) {
+			return fmt.Sprintf("%q does not match %q", p.Name, re.String())
+		}
+		return ""
+	}}
+}
+
+func AmountPositive() Rule {
+	return Rule{"amount_positive", "amount", func(p domain.Payment) string {
+		if !p.Amount.IsPositive() {
+			return fmt.Sprintf("%s is not positive", p.Amount)
+		}
+		return ""
+	}}
+}
+
+// Range is an amount range in major units, a nil bound is not checked.
+type Range struct {
+	Min, Max *big.Rat
+}
+
+// AmountRange limits the amount in major units of the payment currency,
+// currencies without their own range are checked against def.
+func AmountRange(def Range, byCurrency map[domain.Currency]Range) Rule {
+	return Rule{"amount_range", "amount", func(p domain.Payment) string {
+		r, ok := byCurrency[p.Amount.Currency]
+		if !ok {
+			r = def
+		}
+		a := p.Amount.Rat()
+		if r.Min != nil && a.Cmp(r.Min) < 0 {
+			return fmt.Sprintf("%s is less than %s", p.Amount, r.Min.FloatString(2))
+		}
+		if r.Max != nil && a.Cmp(r.Max) > 0 {
+			return fmt.Sprintf("%s is greater than %s", p.Amount, r.Max.FloatString(2))
+		}
+		return ""
+	}}
+}
+
+func CurrencyAllowed(cs ...domain.Currency) Rule {
+	return Rule{"currency_allowed", "currency", func(p domain.Payment) string {
+		if !slices.Contains(cs, p.Amount.Currency) {
+			return fmt.Sprintf("currency %q is not allowed", p.Amount.Currency)
+		}
+		return ""
+	}}
+}
+
+func StatusValid() Rule {
+	return Rule{"status_valid", "status", func(p domain.Payment) string {
+		if !p.Status.Valid() {
+			return fmt.Sprintf("unknown status %q", p.Status)
+		}
+		return ""
+	}}
+}
@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"expvar"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.PaymentValidator = (*Validator)(nil)
+
+// metrics counts checked and rejected payments per rule,
+// the keys are "<rule>.checked" and "<rule>.rejected".
+var metrics = expvar.NewMap("payment_validation")
+
+// Validator runs all rules and collects the reasons of every failed one.
+type Validator struct {
+	rules []Rule
+}
+
+func New(rules ...Rule) *Validator {
+	return &Validator{rules: rules}
+}
+
+// With returns a validator with extra rules, v is not changed.
+func (v *Validator) With(rules ...Rule) *Validator {
+	return New(append(v.Rules(), rules...)...)
+}
+
+func (v *Validator) Rules() []Rule {
+	return append([]Rule(nil), v.rules...)
+}
+
+// Validate returns nil when the payment passes all rules.
+func (v *Validator) Validate(p domain.Payment) []domain.RejectionReason {
+	var reasons []domain.RejectionReason
+	for _, r := range v.rules {
+		metrics.Add(r.Name+".checked", 1)
+		if msg := r.Check(p); msg != "" {
+			metrics.Add(r.Name+".rejected", 1)
+			reasons = append(reasons, r.reason(msg))
+		}
+	}
+	return reasons
+}
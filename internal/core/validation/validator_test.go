@@ -0,0 +1,113 @@
+//go:build !integration
+
+package validation
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromConfig(t *testing.T) {
+	v, err := FromConfig(Config{
+		MinAmount:         "1",
+		MaxAmount:         "100",
+		AllowedCurrencies: []string{"RUB", "USD"},
+		NamePattern:       "^[A-Z]+$",
+		NameMaxLength:     5,
+	})
+	require.NoError(t, err)
+
+	valid := domain.Payment{
+		ID:     uuid.NewString(),
+		Name:   "ABCDE",
+		Amount: domain.NewMoney(1050, domain.CurrencyRUB),
+		Status: domain.StatusCreated,
+	}
+	assert.Empty(t, v.Validate(valid))
+
+	invalid := domain.Payment{
+		ID:     "42",
+		Name:   "abcdef",
+		Amount: domain.NewMoney(100_01, domain.CurrencyEUR),
+		Status: "lost",
+	}
+	var rules []string
+	for _, r := range v.Validate(invalid) {
+		rules = append(rules, r.Rule)
+	}
+	assert.ElementsMatch(t, []string{
+		"id_format", "name_max_length", "name_pattern",
+		"amount_range", "currency_allowed", "status_valid",
+	}, rules)
+
+	empty := domain.Payment{ID: uuid.NewString()}
+	var fields []string
+	for _, r := range v.Validate(empty) {
+		fields = append(fields, r.Field)
+	}
+	assert.Contains(t, fields, "name")
+	assert.Contains(t, fields, "amount")
+}
+
+func TestAmountLimitsPerCurrency(t *testing.T) {
+	v, err := FromConfig(Config{
+		MaxAmount: "100",
+		AmountLimits: map[string]Limits{
+			// config loaders lower case map keys
+			"rub": {Min: "10", Max: "10000"},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		amount domain.Money
+		valid  bool
+	}{
+		{domain.NewMoney(5000_00, domain.CurrencyRUB), true},
+		{domain.NewMoney(5_00, domain.CurrencyRUB), false},
+		{domain.NewMoney(20000_00, domain.CurrencyRUB), false},
+		{domain.NewMoney(5000_00, domain.CurrencyUSD), false},
+		{domain.NewMoney(5_00, domain.CurrencyUSD), true},
+	} {
+		p := domain.Payment{
+			ID:     uuid.NewString(),
+			Name:   "ALICE",
+			Amount: tc.amount,
+			Status: domain.StatusCreated,
+		}
+		assert.Equal(t, tc.valid, len(v.Validate(p)) == 0, "%s", tc.amount)
+	}
+}
+
+func TestFromConfigErrors(t *testing.T) {
+	for _, cfg := range []Config{
+		{MinAmount: "abc"},
+		{MinAmount: "10", MaxAmount: "1"},
+		{AmountLimits: map[string]Limits{"ZZZ": {Max: "1"}}},
+		{AmountLimits: map[string]Limits{"RUB": {Min: "10", Max: "1"}}},
+		{AllowedCurrencies: []string{"ZZZ"}},
+		{NamePattern: "("},
+	} {
+		_, err := FromConfig(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestValidatorMetrics(t *testing.T) {
+	rule := Rule{"test_metrics", "name", func(p domain.Payment) string {
+		if p.Name == "" {
+			return "empty"
+		}
+		return ""
+	}}
+	v := New(rule)
+	v.Validate(domain.Payment{Name: "A"})
+	v.Validate(domain.Payment{})
+
+	assert.Equal(t, "2", metrics.Get("test_metrics.checked").String())
+	assert.Equal(t, "1", metrics.Get("test_metrics.rejected").String())
+}
@@ -0,0 +1,192 @@
+// Package window aggregates payments in event time windows.
+package window
+
+import (
+	"cmp"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.PaymentAggregator = (*Aggregator)(nil)
+
+// metrics counts "late_events" dropped after their windows closed,
+// "future_events" ahead of the clock by more than the skew,
+// "untimed_events" without an event time and "emitted_windows".
+var metrics = expvar.NewMap("payment_aggregation")
+
+// DefaultMaxClockSkew is the skew of producer clocks when
+// Config.MaxClockSkew is zero.
+const DefaultMaxClockSkew = time.Minute
+
+// Config describes the windows. Slide equal to zero or Size makes
+// tumbling windows, a smaller Slide makes overlapping sliding windows.
+// A window closes once an event is seen at End+AllowedLateness.
+// MaxClockSkew bounds the event time that moves the watermark.
+type Config struct {
+	Size            time.Duration
+	Slide           time.Duration
+	AllowedLateness time.Duration
+	MaxClockSkew    time.Duration
+}
+
+type groupKey struct {
+	start    int64
+	payer    string
+	currency domain.Currency
+}
+
+// Aggregator keeps open windows per payer and currency. The watermark
+// is the latest event time seen, so windows close on event time only.
+// An event time ahead of the local clock by more than the skew moves
+// the watermark only to the clock plus the skew, so one future-dated
+// payment does not close every window. Payments without an event time,
+// like the migrated v1 records, are aggregated at the receive time.
+type Aggregator struct {
+	mu        sync.Mutex
+	size      time.Duration
+	slide     time.Duration
+	lateness  time.Duration
+	skew      time.Duration
+	now       func() time.Time
+	watermark time.Time
+	open      map[groupKey]*domain.WindowAggregate
+}
+
+func New(cfg Config) *Aggregator {
+	const op = "window.New"
+
+	if cfg.Slide == 0 {
+		cfg.Slide = cfg.Size
+	}
+	if cfg.MaxClockSkew == 0 {
+		cfg.MaxClockSkew = DefaultMaxClockSkew
+	}
+	if cfg.Size <= 0 || cfg.Slide < 0 || cfg.Slide > cfg.Size ||
+		cfg.AllowedLateness < 0 || cfg.MaxClockSkew < 0 {
+		panic(fmt.Errorf("%s: invalid config %+v", op, cfg)) // develop mistake
+	}
+	return &Aggregator{
+		size:     cfg.Size,
+		slide:    cfg.Slide,
+		lateness: cfg.AllowedLateness,
+		skew:     cfg.MaxClockSkew,
+		now:      time.Now,
+		open:     make(map[groupKey]*domain.WindowAggregate),
+	}
+}
+
+// Add puts the payments into their windows and
+// returns the windows closed by the new watermark.
+func (a *Aggregator) Add(ps []domain.Payment) []domain.WindowAggregate {
+	const op = "window.Aggregator.Add"
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	limit := now.Add(a.skew)
+	for _, p := range ps {
+		// v1 records have no event time, they are migrated with the epoch
+		if p.CreatedAt.UnixMilli() <= 0 {
+			metrics.Add("untimed_events", 1)
+			slog.Debug("event without time", "op", op, "paymentID", p.ID)
+			p.CreatedAt = now
+		}
+		a.add(p)
+
+		t := p.CreatedAt
+		if t.After(limit) {
+			metrics.Add("future_events", 1)
+			slog.Debug("future event", "op", op, "paymentID", p.ID, "createdAt", t)
+			t = limit
+		}
+		if t.After(a.watermark) {
+			a.watermark = t
+		}
+	}
+	return a.closeUntil(a.watermark)
+}
+
+// Flush returns all open windows, it is meant for shutdown.
+func (a *Aggregator) Flush() []domain.WindowAggregate {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.closeUntil(time.Time{})
+}
+
+func (a *Aggregator) add(p domain.Payment) {
+	const op = "window.Aggregator.add"
+
+	t := p.CreatedAt
+	for start := t.Truncate(a.slide); start.After(t.Add(-a.size)); start = start.Add(-a.slide) {
+		end := start.Add(a.size)
+		if a.closed(end) {
+			metrics.Add("late_events", 1)
+			slog.Debug("late event", "op", op, "paymentID", p.ID, "windowEnd", end)
+			continue
+		}
+
+		k := groupKey{start.UnixNano(), p.Name, p.Amount.Currency}
+		w, ok := a.open[k]
+		if !ok {
+			a.open[k] = &domain.WindowAggregate{
+				Payer:    p.Name,
+				Currency: p.Amount.Currency,
+				Start:    start,
+				End:      end,
+				Count:    1,
+				Sum:      p.Amount,
+				Min:      p.Amount,
+				Max:      p.Amount,
+			}
+			continue
+		}
+
+		sum, err := w.Sum.Add(p.Amount)
+		if err != nil {
+			slog.Warn("skip payment", "op", op, "paymentID", p.ID, "err", err)
+			continue
+		}
+		w.Count++
+		w.Sum = sum
+		if p.Amount.Minor < w.Min.Minor {
+			w.Min = p.Amount
+		}
+		if p.Amount.Minor > w.Max.Minor {
+			w.Max = p.Amount
+		}
+	}
+}
+
+func (a *Aggregator) closed(end time.Time) bool {
+	return !end.Add(a.lateness).After(a.watermark)
+}
+
+// closeUntil removes and returns windows closed by the watermark,
+// the zero watermark closes all windows.
+func (a *Aggregator) closeUntil(watermark time.Time) []domain.WindowAggregate {
+	var out []domain.WindowAggregate
+	for k, w := range a.open {
+		if watermark.IsZero() || !w.End.Add(a.lateness).After(watermark) {
+			out = append(out, *w)
+			delete(a.open, k)
+		}
+	}
+	metrics.Add("emitted_windows", int64(len(out)))
+
+	slices.SortFunc(out, func(x, y domain.WindowAggregate) int {
+		return cmp.Or(
+			x.Start.Compare(y.Start),
+			cmp.Compare(x.Payer, y.Payer),
+			cmp.Compare(x.Currency, y.Currency),
+		)
+	})
+	return out
+}
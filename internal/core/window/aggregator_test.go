@@ -0,0 +1,121 @@
+//go:build !integration
+
+package window
+
+import (
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func payment(name string, minor int64, c domain.Currency, at time.Duration) domain.Payment {
+	return domain.Payment{
+		ID:        name,
+		Name:      name,
+		Amount:    domain.NewMoney(minor, c),
+		Status:    domain.StatusCreated,
+		CreatedAt: t0.Add(at),
+	}
+}
+
+func TestTumblingWindows(t *testing.T) {
+	a := New(Config{Size: time.Minute, AllowedLateness: 10 * time.Second})
+
+	closed := a.Add([]domain.Payment{
+		payment("A", 100, domain.CurrencyRUB, 5*time.Second),
+		payment("A", 300, domain.CurrencyRUB, 20*time.Second),
+		payment("A", 50, domain.CurrencyUSD, 30*time.Second),
+		payment("B", 700, domain.CurrencyRUB, 65*time.Second),
+	})
+	assert.Empty(t, closed, "lateness keeps the first window open")
+
+	// late but within the allowed lateness
+	closed = a.Add([]domain.Payment{
+		payment("A", 200, domain.CurrencyRUB, 50*time.Second),
+		payment("B", 1, domain.CurrencyRUB, 71*time.Second),
+	})
+	require.Len(t, closed, 2)
+
+	rub := closed[0]
+	assert.Equal(t, "A", rub.Payer)
+	assert.Equal(t, domain.CurrencyRUB, rub.Currency)
+	assert.Equal(t, t0, rub.Start)
+	assert.Equal(t, t0.Add(time.Minute), rub.End)
+	assert.Equal(t, int64(3), rub.Count)
+	assert.Equal(t, domain.NewMoney(600, domain.CurrencyRUB), rub.Sum)
+	assert.Equal(t, domain.NewMoney(100, domain.CurrencyRUB), rub.Min)
+	assert.Equal(t, domain.NewMoney(300, domain.CurrencyRUB), rub.Max)
+	assert.Equal(t, domain.CurrencyUSD, closed[1].Currency)
+
+	// too late, the window is closed
+	assert.Empty(t, a.Add([]domain.Payment{
+		payment("A", 1, domain.CurrencyRUB, 10*time.Second),
+	}))
+
+	flushed := a.Flush()
+	require.Len(t, flushed, 1)
+	assert.Equal(t, "B", flushed[0].Payer)
+	assert.Equal(t, int64(2), flushed[0].Count)
+	assert.Empty(t, a.Flush())
+}
+
+func TestSlidingWindows(t *testing.T) {
+	a := New(Config{Size: time.Minute, Slide: 30 * time.Second})
+
+	a.Add([]domain.Payment{payment("A", 100, domain.CurrencyRUB, 40*time.Second)})
+	ws := a.Flush()
+
+	require.Len(t, ws, 2)
+	assert.Equal(t, t0, ws[0].Start)
+	assert.Equal(t, t0.Add(30*time.Second), ws[1].Start)
+	for _, w := range ws {
+		assert.Equal(t, int64(1), w.Count)
+	}
+}
+
+func TestFutureEventBoundsWatermark(t *testing.T) {
+	a := New(Config{Size: time.Minute, MaxClockSkew: time.Second})
+	a.now = func() time.Time { return t0.Add(30 * time.Second) }
+
+	closed := a.Add([]domain.Payment{
+		payment("A", 100, domain.CurrencyRUB, 5*time.Second),
+		payment("B", 100, domain.CurrencyRUB, 24*time.Hour),
+	})
+	assert.Empty(t, closed, "a future-dated payment closes no window")
+
+	a.now = func() time.Time { return t0.Add(90 * time.Second) }
+	closed = a.Add([]domain.Payment{
+		payment("A", 200, domain.CurrencyRUB, 10*time.Second),
+		payment("C", 1, domain.CurrencyRUB, 61*time.Second),
+	})
+	require.Len(t, closed, 1)
+	assert.Equal(t, "A", closed[0].Payer)
+	assert.Equal(t, int64(2), closed[0].Count)
+}
+
+func TestUntimedEventAtReceiveTime(t *testing.T) {
+	a := New(Config{Size: time.Minute})
+	a.now = func() time.Time { return t0.Add(30 * time.Second) }
+
+	p := payment("A", 100, domain.CurrencyRUB, 0)
+	p.CreatedAt = time.UnixMilli(0)
+	assert.Empty(t, a.Add([]domain.Payment{
+		payment("B", 1, domain.CurrencyRUB, 20*time.Second), p,
+	}))
+
+	ws := a.Flush()
+	require.Len(t, ws, 2)
+	assert.Equal(t, "A", ws[0].Payer)
+	assert.Equal(t, t0, ws[0].Start)
+	assert.Equal(t, int64(1), ws[0].Count)
+}
+
+func TestNewPanicsOnInvalidConfig(t *testing.T) {
+	assert.Panics(t, func() { New(Config{}) })
+	assert.Panics(t, func() { New(Config{Size: time.Second, Slide: time.Minute}) })
+}
@@ -0,0 +1,217 @@
+// Package loadtest drives a payment producer at a target rate
+// and measures produce and end-to-end latency.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/niksmo/cloud-integration/internal/core/port"
+)
+
+var _ port.PaymentReceiver = (*Runner)(nil)
+
+// pacerTick is how often the pacer hands out send tokens,
+// rates above 1/pacerTick get several tokens per tick.
+const pacerTick = time.Millisecond
+
+// drainCheck is how often received payments are checked while draining.
+const drainCheck = 50 * time.Millisecond
+
+// Config of a load test. Rate is payments per second of all Senders
+// together, Drain is how long consumed records are awaited after
+// the last payment is sent.
+type Config struct {
+	Rate     float64
+	Senders  int
+	Duration time.Duration
+	Drain    time.Duration
+}
+
+// Runner sends payments with the producer and receives them back as
+// port.PaymentReceiver, so it is plugged into a consumer of the topic.
+type Runner struct {
+	producer port.PaymentProducer
+	cfg      Config
+
+	mu      sync.Mutex
+	sentAt  map[string]time.Time
+	produce []time.Duration
+	e2e     []time.Duration
+
+	sent, errs atomic.Int64
+}
+
+func New(p port.PaymentProducer, cfg Config) *Runner {
+	const op = "loadtest.New"
+
+	if p == nil || cfg.Rate <= 0 || cfg.Senders <= 0 || cfg.Duration <= 0 || cfg.Drain < 0 {
+		panic(fmt.Errorf("%s: invalid config %+v", op, cfg)) // develop mistake
+	}
+	return &Runner{
+		producer: p,
+		cfg:      cfg,
+		sentAt:   make(map[string]time.Time),
+	}
+}
+
+// Run sends payments for the configured duration, waits for them to
+// be consumed and returns the report. A canceled context stops both
+// phases early, the report covers what was measured so far.
+func (r *Runner) Run(ctx context.Context) Report {
+	const op = "Runner.Run"
+	log := slog.With("op", op)
+
+	log.Info("start load test", "config", r.cfg)
+	start := time.Now()
+
+	sendCtx, cancel := context.WithTimeout(ctx, r.cfg.Duration)
+	defer cancel()
+
+	tokens := make(chan struct{}, r.cfg.Senders)
+	go r.pace(sendCtx, tokens)
+
+	var wg sync.WaitGroup
+	for range r.cfg.Senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range tokens {
+				r.send(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+	sendTime := time.Since(start)
+
+	log.Info("sending is over, draining", "sent", r.sent.Load(), "errors", r.errs.Load())
+	r.drain(ctx)
+
+	return r.report(start, sendTime)
+}
+
+// ReceivePayments records end-to-end latency of
+// the payments sent by this runner, others are ignored.
+func (r *Runner) ReceivePayments(es []domain.PaymentEnvelope) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range es {
+		sentAt, ok := r.sentAt[e.Payment.ID]
+		if !ok {
+			continue
+		}
+		delete(r.sentAt, e.Payment.ID)
+		r.e2e = append(r.e2e, now.Sub(sentAt))
+	}
+}
+
+// pace hands out Rate tokens per second until the context is done.
+func (r *Runner) pace(ctx context.Context, tokens chan<- struct{}) {
+	defer close(tokens)
+
+	ticker := time.NewTicker(max(pacerTick, time.Duration(float64(time.Second)/r.cfg.Rate)))
+	defer ticker.Stop()
+	start := time.Now()
+	var issued int64
+	for {
+		due := int64(time.Since(start).Seconds()*r.cfg.Rate) + 1
+		for ; issued < due; issued++ {
+			select {
+			case <-ctx.Done():
+				return
+			case tokens <- struct{}{}:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) send(ctx context.Context) {
+	const op = "Runner.send"
+
+	p := domain.NewPayment(
+		"LOADTEST", domain.NewMoney(100+rand.Int64N(100_000), domain.CurrencyRUB),
+	)
+
+	start := time.Now()
+	r.mu.Lock()
+	r.sentAt[p.ID] = start
+	r.mu.Unlock()
+
+	err := r.producer.ProducePayment(ctx, p)
+	d := time.Since(start)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		delete(r.sentAt, p.ID)
+		r.errs.Add(1)
+		if !errors.Is(err, context.Canceled) {
+			slog.Error("failed to produce payment", "op", op, "err", err)
+		}
+		return
+	}
+	r.sent.Add(1)
+	r.produce = append(r.produce, d)
+}
+
+// drain waits until every sent payment is received or the drain time is over.
+func (r *Runner) drain(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Drain)
+	defer cancel()
+
+	ticker := time.NewTicker(drainCheck)
+	defer ticker.Stop()
+	for {
+		r.mu.Lock()
+		pending := len(r.sentAt)
+		r.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) report(start time.Time, sendTime time.Duration) Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sent, errs := r.sent.Load(), r.errs.Load()
+	rep := Report{
+		Started:    start,
+		Duration:   sendTime,
+		TargetRate: r.cfg.Rate,
+		Senders:    r.cfg.Senders,
+		Sent:       sent,
+		Errors:     errs,
+		Received:   int64(len(r.e2e)),
+		Lost:       int64(len(r.sentAt)),
+		Produce:    newLatency(r.produce),
+		EndToEnd:   newLatency(r.e2e),
+	}
+	if total := sent + errs; total != 0 {
+		rep.ErrorRate = float64(errs) / float64(total)
+	}
+	if sendTime > 0 {
+		rep.Throughput = float64(sent) / sendTime.Seconds()
+	}
+	return rep
+}
@@ -0,0 +1,79 @@
+//go:build !integration
+
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/niksmo/cloud-integration/internal/core/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoProducer delivers produced payments back to
+// the runner and fails every failEvery-th of them.
+type echoProducer struct {
+	r         *Runner
+	n         atomic.Int64
+	failEvery int64
+}
+
+func (p *echoProducer) ProducePayment(_ context.Context, pm domain.Payment) error {
+	if p.n.Add(1)%p.failEvery == 0 {
+		return errors.New("broker is down")
+	}
+	go func() {
+		time.Sleep(time.Millisecond)
+		p.r.ReceivePayments([]domain.PaymentEnvelope{{Payment: pm}})
+	}()
+	return nil
+}
+
+func TestRunnerReport(t *testing.T) {
+	p := &echoProducer{failEvery: 10}
+	r := New(p, Config{
+		Rate: 200, Senders: 4, Duration: 500 * time.Millisecond, Drain: time.Second,
+	})
+	p.r = r
+
+	rep := r.Run(context.Background())
+
+	// the pacer never runs ahead of the rate, a slow machine sends less
+	total := rep.Sent + rep.Errors
+	assert.Positive(t, total)
+	assert.LessOrEqual(t, total, int64(101))
+	assert.Equal(t, total/10, rep.Errors)
+	assert.Equal(t, rep.Sent, rep.Received)
+	assert.Zero(t, rep.Lost)
+	assert.Equal(t, rep.Sent, rep.Produce.Count)
+	assert.GreaterOrEqual(t, rep.EndToEnd.P50, 1.0)
+	assert.LessOrEqual(t, rep.EndToEnd.P50, rep.EndToEnd.P99)
+
+	var table, js bytes.Buffer
+	require.NoError(t, rep.WriteTable(&table))
+	assert.Contains(t, table.String(), "end-to-end")
+	require.NoError(t, rep.WriteJSON(&js))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(js.Bytes(), &decoded))
+	assert.Equal(t, rep.Sent, decoded.Sent)
+}
+
+func TestPercentile(t *testing.T) {
+	ds := make([]time.Duration, 100)
+	for i := range ds {
+		ds[i] = time.Duration(100-i) * time.Millisecond
+	}
+	l := newLatency(ds)
+	assert.Equal(t, 50.0, l.P50)
+	assert.Equal(t, 95.0, l.P95)
+	assert.Equal(t, 99.0, l.P99)
+	assert.Equal(t, 100.0, l.Max)
+	assert.Equal(t, 50.5, l.Mean)
+	assert.Equal(t, Latency{}, newLatency(nil))
+}
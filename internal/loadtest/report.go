@@ -0,0 +1,103 @@
+package loadtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"text/tabwriter"
+	"time"
+)
+
+// Latency percentiles in milliseconds.
+type Latency struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean_ms"`
+	P50   float64 `json:"p50_ms"`
+	P95   float64 `json:"p95_ms"`
+	P99   float64 `json:"p99_ms"`
+	Max   float64 `json:"max_ms"`
+}
+
+func newLatency(ds []time.Duration) Latency {
+	if len(ds) == 0 {
+		return Latency{}
+	}
+	sorted := slices.Clone(ds)
+	slices.Sort(sorted)
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return Latency{
+		Count: int64(len(sorted)),
+		Mean:  ms(sum / time.Duration(len(sorted))),
+		P50:   ms(percentile(sorted, 50)),
+		P95:   ms(percentile(sorted, 95)),
+		P99:   ms(percentile(sorted, 99)),
+		Max:   ms(sorted[len(sorted)-1]),
+	}
+}
+
+// percentile uses the nearest rank of sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Report of a load test. Throughput is produced payments per second,
+// Lost are produced payments not consumed within the drain time.
+type Report struct {
+	Started    time.Time     `json:"started"`
+	Duration   time.Duration `json:"duration_ns"`
+	TargetRate float64       `json:"target_rate"`
+	Senders    int           `json:"senders"`
+	Sent       int64         `json:"sent"`
+	Errors     int64         `json:"errors"`
+	ErrorRate  float64       `json:"error_rate"`
+	Throughput float64       `json:"throughput"`
+	Received   int64         `json:"received"`
+	Lost       int64         `json:"lost"`
+	Produce    Latency       `json:"produce_latency"`
+	EndToEnd   Latency       `json:"end_to_end_latency"`
+}
+
+// WriteTable writes the report for humans.
+func (r Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "duration\t%s\t\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(tw, "senders\t%d\t\n", r.Senders)
+	fmt.Fprintf(tw, "target rate\t%.1f/s\t\n", r.TargetRate)
+	fmt.Fprintf(tw, "throughput\t%.1f/s\t\n", r.Throughput)
+	fmt.Fprintf(tw, "sent\t%d\t\n", r.Sent)
+	fmt.Fprintf(tw, "errors\t%d (%.2f%%)\t\n", r.Errors, 100*r.ErrorRate)
+	fmt.Fprintf(tw, "received\t%d\t\n", r.Received)
+	fmt.Fprintf(tw, "lost\t%d\t\n", r.Lost)
+	fmt.Fprintln(tw, "\t\t")
+	fmt.Fprintln(tw, "latency, ms\tcount\tmean\tp50\tp95\tp99\tmax\t")
+	for _, row := range []struct {
+		name string
+		l    Latency
+	}{
+		{"produce", r.Produce},
+		{"end-to-end", r.EndToEnd},
+	} {
+		l := row.l
+		fmt.Fprintf(
+			tw, "%s\t%d\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t\n",
+			row.name, l.Count, l.Mean, l.P50, l.P95, l.P99, l.Max,
+		)
+	}
+	return tw.Flush()
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
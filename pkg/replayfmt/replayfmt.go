@@ -0,0 +1,33 @@
+// Package replayfmt names the formats of the replayed payment files,
+// it is shared by the config validation and the replayer.
+package replayfmt
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	CSV   = "csv"
+	JSONL = "jsonl"
+)
+
+// Formats returns all replay formats.
+func Formats() []string {
+	return []string{CSV, JSONL}
+}
+
+// Of returns the format of the file extension. Files without
+// extension are JSON Lines, so the files saved by the consumer to HDFS
+// are replayed as they are.
+func Of(path string) (string, error) {
+	switch ext := strings.TrimPrefix(filepath.Ext(path), "."); ext {
+	case "", "json", "ndjson", JSONL:
+		return JSONL, nil
+	case CSV:
+		return CSV, nil
+	default:
+		return "", fmt.Errorf("unknown replay format of %q, set the format", path)
+	}
+}
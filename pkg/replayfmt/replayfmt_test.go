@@ -0,0 +1,27 @@
+//go:build !integration
+
+package replayfmt
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOf(t *testing.T) {
+	for path, want := range map[string]string{
+		"payments.csv":   CSV,
+		"payments.jsonl": JSONL,
+		"payments.json":  JSONL,
+		// the files the consumer saves to HDFS have no extension
+		"/payments/payments-0e3f": JSONL,
+	} {
+		got, err := Of(path)
+		require.NoError(t, err, path)
+		assert.Equal(t, want, got, path)
+	}
+
+	_, err := Of("payments.xlsx")
+	assert.Error(t, err)
+}
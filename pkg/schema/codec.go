@@ -0,0 +1,148 @@
+package schema
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+//go:embed avro/*.avsc proto/*.proto json/*.json
+var schemaFS embed.FS
+
+var ErrCodecNotFound = errors.New("codec not found")
+
+// DefaultRegistry holds codecs of the schemas shipped with the package.
+var DefaultRegistry = NewRegistry()
+
+// Codec encodes and decodes T with an Avro schema that is parsed once.
+type Codec[T any] struct {
+	subject string
+	version int
+	text    string
+	schema  avro.Schema
+}
+
+func NewCodec[T any](subject string, version int, text string) (Codec[T], error) {
+	s, err := avro.Parse(text)
+	if err != nil {
+		return Codec[T]{}, fmt.Errorf(
+			"failed to parse schema %q version %d: %w", subject, version, err,
+		)
+	}
+	return Codec[T]{subject, version, text, s}, nil
+}
+
+func (c Codec[T]) Subject() string     { return c.subject }
+func (c Codec[T]) Version() int        { return c.version }
+func (c Codec[T]) Schema() avro.Schema { return c.schema }
+
+// SRSchema returns the schema in the form accepted by the schema registry.
+func (c Codec[T]) SRSchema() sr.Schema {
+	return sr.Schema{Type: sr.TypeAvro, Schema: c.text}
+}
+
+func (c Codec[T]) Encode(v T) ([]byte, error) {
+	return avro.Marshal(c.schema, v)
+}
+
+func (c Codec[T]) Decode(data []byte) (T, error) {
+	var v T
+	err := avro.Unmarshal(c.schema, data, &v)
+	return v, err
+}
+
+// EncodeFn adapts the codec to sr.EncodeFn, v must be T or *T.
+func (c Codec[T]) EncodeFn() func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		switch t := v.(type) {
+		case T:
+			return c.Encode(t)
+		case *T:
+			return c.Encode(*t)
+		default:
+			return nil, fmt.Errorf("codec %q: unexpected type %T", c.subject, v)
+		}
+	}
+}
+
+// DecodeFn adapts the codec to sr.DecodeFn.
+func (c Codec[T]) DecodeFn() func([]byte, any) error {
+	return func(data []byte, v any) error {
+		return avro.Unmarshal(c.schema, data, v)
+	}
+}
+
+type codecKey struct {
+	subject string
+	version int
+}
+
+// Registry holds codecs keyed by subject and version.
+type Registry struct {
+	mu     sync.RWMutex
+	codecs map[codecKey]any
+}
+
+func NewRegistry() *Registry {
+	return &Registry{codecs: make(map[codecKey]any)}
+}
+
+// Register parses the schema text and stores the codec,
+// a codec registered with the same subject and version is replaced.
+func Register[T any](
+	r *Registry, subject string, version int, text string,
+) (Codec[T], error) {
+	c, err := NewCodec[T](subject, version, text)
+	if err != nil {
+		return Codec[T]{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codecs[codecKey{subject, version}] = c
+	return c, nil
+}
+
+// MustRegister is Register that panics on error,
+// it is meant for schemas shipped with the package.
+func MustRegister[T any](
+	r *Registry, subject string, version int, text string,
+) Codec[T] {
+	c, err := Register[T](r, subject, version, text)
+	if err != nil {
+		panic(fmt.Errorf("contact with package dev team: %w", err))
+	}
+	return c
+}
+
+func Lookup[T any](r *Registry, subject string, version int) (Codec[T], error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.codecs[codecKey{subject, version}]
+	if !ok {
+		return Codec[T]{}, fmt.Errorf(
+			"%w: subject %q version %d", ErrCodecNotFound, subject, version,
+		)
+	}
+	typed, ok := c.(Codec[T])
+	if !ok {
+		var zero T
+		return Codec[T]{}, fmt.Errorf(
+			"codec %q version %d does not encode %T", subject, version, zero,
+		)
+	}
+	return typed, nil
+}
+
+func mustReadFile(name string) string {
+	data, err := schemaFS.ReadFile(name)
+	if err != nil {
+		panic(fmt.Errorf("contact with package dev team: %w", err))
+	}
+	return string(data)
+}
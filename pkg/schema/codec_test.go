@@ -0,0 +1,115 @@
+//go:build !integration
+
+package schema
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("LookupShipped", func(t *testing.T) {
+		c, err := Lookup[PaymentV2](DefaultRegistry, PaymentSubject, 2)
+		require.NoError(t, err)
+		assert.Equal(t, 2, c.Version())
+	})
+
+	t.Run("LookupNotFound", func(t *testing.T) {
+		_, err := Lookup[PaymentV2](DefaultRegistry, PaymentSubject, 100)
+		require.ErrorIs(t, err, ErrCodecNotFound)
+	})
+
+	t.Run("LookupWrongType", func(t *testing.T) {
+		_, err := Lookup[PaymentV1](DefaultRegistry, PaymentSubject, 2)
+		require.Error(t, err)
+	})
+
+	t.Run("RegisterInvalid", func(t *testing.T) {
+		_, err := Register[PaymentV1](NewRegistry(), "broken", 1, `{"type":`)
+		require.Error(t, err)
+	})
+
+	t.Run("CodecRoundTrip", func(t *testing.T) {
+		want := PaymentV1{"id", "name", 42.5}
+		data, err := PaymentV1Codec.Encode(want)
+		require.NoError(t, err)
+		got, err := PaymentV1Codec.Decode(data)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	})
+
+	t.Run("EncodeFnPointer", func(t *testing.T) {
+		_, err := PaymentV1Codec.EncodeFn()(&PaymentV1{"id", "name", 1})
+		require.NoError(t, err)
+		_, err = PaymentV1Codec.EncodeFn()(PaymentV2{})
+		require.Error(t, err)
+	})
+}
+
+func benchPayment() PaymentV2 {
+	return PaymentV2{
+		ID:        "0c3e5f51-9a4f-4a43-a1cf-6d0bd5e0d8d5",
+		Name:      "ABCDE",
+		Amount:    big.NewRat(12345, 100),
+		Currency:  "RUB",
+		CreatedAt: time.UnixMilli(1_700_000_000_000),
+	}
+}
+
+// BenchmarkEncodeParsePerRecord is the encoding
+// used before codecs: the schema is parsed per record.
+func BenchmarkEncodeParsePerRecord(b *testing.B) {
+	p := benchPayment()
+	b.ReportAllocs()
+	for b.Loop() {
+		s, err := avro.Parse(PaymentSchemaTextV2)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := avro.Marshal(s, p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeCodec(b *testing.B) {
+	p := benchPayment()
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := PaymentV2Codec.Encode(p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeParsePerRecord(b *testing.B) {
+	data, err := PaymentV2Codec.Encode(benchPayment())
+	require.NoError(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		s, err := avro.Parse(PaymentSchemaTextV2)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var p PaymentV2
+		if err := avro.Unmarshal(s, data, &p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecodeCodec(b *testing.B) {
+	data, err := PaymentV2Codec.Encode(benchPayment())
+	require.NoError(b, err)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := PaymentV2Codec.Decode(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package schema
+
+import (
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+// Format is a serialization format of record values.
+type Format string
+
+const (
+	FormatAvro     Format = "avro"
+	FormatProtobuf Format = "protobuf"
+	FormatJSON     Format = "json"
+)
+
+// Content types of values in the Confluent wire format.
+const (
+	ContentTypeAvro     = "application/vnd.confluent.avro"
+	ContentTypeProtobuf = "application/vnd.confluent.protobuf"
+	ContentTypeJSON     = "application/vnd.confluent.json"
+)
+
+// Serialization is everything needed to register a schema and
+// to add it to sr.Serde. Every format encodes and decodes PaymentV2,
+// so producers and consumers do not depend on the format.
+type Serialization struct {
+	Format Format
+	// RecordName is the fully qualified record name used by the
+	// record subject name strategies: the full message name for
+	// protobuf and the schema title for JSON.
+	RecordName  string
+	Schema      sr.Schema
+	ContentType string
+	EncodeFn    func(any) ([]byte, error)
+	DecodeFn    func([]byte, any) error
+	// Index is the protobuf message index, nil for other formats.
+	Index []int
+}
+
+// EncodingOpts returns options for sr.Serde.Register.
+func (s Serialization) EncodingOpts() []sr.EncodingOpt {
+	opts := []sr.EncodingOpt{
+		sr.EncodeFn(s.EncodeFn),
+		sr.DecodeFn(s.DecodeFn),
+	}
+	if s.Index != nil {
+		opts = append(opts, sr.Index(s.Index...))
+	}
+	return opts
+}
+
+// PaymentSerialization returns the latest payment schema
+// serialization in the format, empty format is Avro.
+func PaymentSerialization(f Format) (Serialization, error) {
+	switch f {
+	case FormatAvro, "":
+		return Serialization{
+			Format:      FormatAvro,
+			RecordName:  PaymentRecordName,
+			Schema:      PaymentSchemaV2,
+			ContentType: ContentTypeAvro,
+			EncodeFn:    PaymentV2Codec.EncodeFn(),
+			DecodeFn:    PaymentV2Codec.DecodeFn(),
+		}, nil
+	case FormatProtobuf:
+		return Serialization{
+			Format:     FormatProtobuf,
+			RecordName: "transactions.Payment",
+			Schema: sr.Schema{
+				Type:   sr.TypeProtobuf,
+				Schema: PaymentSchemaTextProto,
+			},
+			ContentType: ContentTypeProtobuf,
+			EncodeFn:    PaymentProtoEncodeFn(),
+			DecodeFn:    PaymentProtoDecodeFn(),
+			Index:       []int{0},
+		}, nil
+	case FormatJSON:
+		return Serialization{
+			Format:     FormatJSON,
+			RecordName: PaymentRecordName,
+			Schema: sr.Schema{
+				Type:   sr.TypeJSON,
+				Schema: PaymentSchemaTextJSON,
+			},
+			ContentType: ContentTypeJSON,
+			EncodeFn:    PaymentJSONEncodeFn(),
+			DecodeFn:    PaymentJSONDecodeFn(),
+		}, nil
+	default:
+		return Serialization{}, fmt.Errorf("unsupported format %q", f)
+	}
+}
+
+// PaymentKeySerialization returns the payment key schema
+// serialization in the format, empty format is Avro.
+func PaymentKeySerialization(f Format) (Serialization, error) {
+	switch f {
+	case FormatAvro, "":
+		return Serialization{
+			Format:      FormatAvro,
+			RecordName:  PaymentKeyRecordName,
+			Schema:      PaymentKeyCodec.SRSchema(),
+			ContentType: ContentTypeAvro,
+			EncodeFn:    PaymentKeyCodec.EncodeFn(),
+			DecodeFn:    PaymentKeyCodec.DecodeFn(),
+		}, nil
+	case FormatProtobuf:
+		return Serialization{
+			Format:     FormatProtobuf,
+			RecordName: "transactions.PaymentKey",
+			Schema: sr.Schema{
+				Type:   sr.TypeProtobuf,
+				Schema: PaymentKeySchemaTextProto,
+			},
+			ContentType: ContentTypeProtobuf,
+			EncodeFn:    PaymentKeyProtoEncodeFn(),
+			DecodeFn:    PaymentKeyProtoDecodeFn(),
+			Index:       []int{0},
+		}, nil
+	case FormatJSON:
+		return Serialization{
+			Format:     FormatJSON,
+			RecordName: PaymentKeyRecordName,
+			Schema: sr.Schema{
+				Type:   sr.TypeJSON,
+				Schema: PaymentKeySchemaTextJSON,
+			},
+			ContentType: ContentTypeJSON,
+			EncodeFn:    PaymentKeyJSONEncodeFn(),
+			DecodeFn:    PaymentKeyJSONDecodeFn(),
+		}, nil
+	default:
+		return Serialization{}, fmt.Errorf("unsupported format %q", f)
+	}
+}
+
+// RefundSerialization returns the refund schema
+// serialization in the format, empty format is Avro.
+func RefundSerialization(f Format) (Serialization, error) {
+	switch f {
+	case FormatAvro, "":
+		return Serialization{
+			Format:      FormatAvro,
+			RecordName:  RefundRecordName,
+			Schema:      RefundCodec.SRSchema(),
+			ContentType: ContentTypeAvro,
+			EncodeFn:    RefundCodec.EncodeFn(),
+			DecodeFn:    RefundCodec.DecodeFn(),
+		}, nil
+	case FormatProtobuf:
+		return Serialization{
+			Format:     FormatProtobuf,
+			RecordName: "transactions.Refund",
+			Schema: sr.Schema{
+				Type:   sr.TypeProtobuf,
+				Schema: RefundSchemaTextProto,
+			},
+			ContentType: ContentTypeProtobuf,
+			EncodeFn:    RefundProtoEncodeFn(),
+			DecodeFn:    RefundProtoDecodeFn(),
+			Index:       []int{0},
+		}, nil
+	case FormatJSON:
+		return Serialization{
+			Format:     FormatJSON,
+			RecordName: RefundRecordName,
+			Schema: sr.Schema{
+				Type:   sr.TypeJSON,
+				Schema: RefundSchemaTextJSON,
+			},
+			ContentType: ContentTypeJSON,
+			EncodeFn:    RefundJSONEncodeFn(),
+			DecodeFn:    RefundJSONDecodeFn(),
+		}, nil
+	default:
+		return Serialization{}, fmt.Errorf("unsupported format %q", f)
+	}
+}
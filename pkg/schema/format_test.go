@@ -0,0 +1,112 @@
+//go:build !integration
+
+package schema
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/sr"
+)
+
+func TestPaymentSerialization(t *testing.T) {
+	desc := "refund"
+	want := PaymentV2{
+		ID:          "id",
+		Name:        "name",
+		Amount:      big.NewRat(-505, 100),
+		Currency:    "USD",
+		CreatedAt:   time.UnixMilli(1_700_000_000_123),
+		Description: &desc,
+	}
+
+	formats := []Format{FormatAvro, FormatProtobuf, FormatJSON}
+	for _, f := range formats {
+		t.Run(string(f), func(t *testing.T) {
+			s, err := PaymentSerialization(f)
+			require.NoError(t, err)
+
+			var serde sr.Serde
+			serde.Register(1, PaymentV2{}, s.EncodingOpts()...)
+
+			data, err := serde.Encode(want)
+			require.NoError(t, err)
+
+			got, err := serde.DecodeNew(data)
+			require.NoError(t, err)
+			p := got.(*PaymentV2)
+
+			amount, err := p.AmountRat()
+			require.NoError(t, err)
+			assert.Equal(t, "-5.05", RatString(amount))
+			assert.Equal(t, want.ID, p.ID)
+			assert.Equal(t, want.Currency, p.Currency)
+			assert.True(t, want.CreatedAt.Equal(p.CreatedAt))
+			assert.Equal(t, desc, *p.Description)
+		})
+	}
+
+	t.Run("ProtobufMessageIndex", func(t *testing.T) {
+		s, err := PaymentSerialization(FormatProtobuf)
+		require.NoError(t, err)
+		var serde sr.Serde
+		serde.Register(1, PaymentV2{}, s.EncodingOpts()...)
+
+		data, err := serde.Encode(want)
+		require.NoError(t, err)
+		// magic byte, 4 bytes of schema id, first message index shortcut
+		assert.Equal(t, []byte{0, 0, 0, 0, 1, 0}, data[:6])
+	})
+
+	t.Run("Key", func(t *testing.T) {
+		for _, f := range formats {
+			s, err := PaymentKeySerialization(f)
+			require.NoError(t, err)
+
+			var serde sr.Serde
+			serde.Register(2, PaymentKey{}, s.EncodingOpts()...)
+
+			data, err := serde.Encode(PaymentKey{ID: "id"})
+			require.NoError(t, err)
+			got, err := serde.DecodeNew(data)
+			require.NoError(t, err)
+			assert.Equal(t, "id", got.(*PaymentKey).ID, f)
+		}
+	})
+
+	t.Run("Refund", func(t *testing.T) {
+		want := Refund{
+			ID:        "id",
+			Name:      "name",
+			Amount:    big.NewRat(505, 100),
+			Currency:  "USD",
+			CreatedAt: time.UnixMilli(1_700_000_000_123),
+			Reason:    &desc,
+		}
+		for _, f := range formats {
+			s, err := RefundSerialization(f)
+			require.NoError(t, err)
+
+			var serde sr.Serde
+			serde.Register(3, Refund{}, s.EncodingOpts()...)
+
+			data, err := serde.Encode(want)
+			require.NoError(t, err)
+			got, err := serde.DecodeNew(data)
+			require.NoError(t, err)
+			r := got.(*Refund)
+			assert.Equal(t, RefundRecordName, r.RecordName(), f)
+			assert.Equal(t, "5.05", RatString(r.Amount), f)
+			assert.Equal(t, want.ID, r.ID, f)
+			assert.Equal(t, desc, *r.Reason, f)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		_, err := PaymentSerialization("xml")
+		require.Error(t, err)
+	})
+}
@@ -1,22 +1,24 @@
 package schema
 
-import (
-	"fmt"
+import "github.com/hamba/avro/v2"
 
-	"github.com/hamba/avro/v2"
-	"github.com/twmb/franz-go/pkg/sr"
-)
+// PaymentRecordName is the fully qualified name of the payment record,
+// it is also the registry key of the payment schemas.
+const PaymentRecordName = "transactions.payment"
+
+// PaymentSubject is the registry key of the payment schemas.
+const PaymentSubject = PaymentRecordName
+
+// Record is a schema value that knows its fully qualified record name.
+type Record interface {
+	RecordName() string
+}
 
-const PaymentSchemaTextV1 = `{
-	"type": "record",
-	"namespace": "transactions",
-	"name": "payment",
-	"fields" : [
-		{"name": "id", "type": "string"},
-		{"name": "name", "type": "string"},
-		{"name": "amount", "type": "double"}
-	]
-}`
+var PaymentSchemaTextV1 = mustReadFile("avro/payment_v1.avsc")
+
+var PaymentV1Codec = MustRegister[PaymentV1](
+	DefaultRegistry, PaymentSubject, 1, PaymentSchemaTextV1,
+)
 
 type PaymentV1 struct {
 	ID     string  `avro:"id"`
@@ -24,33 +26,18 @@ type PaymentV1 struct {
 	Amount float64 `avro:"amount"`
 }
 
-var PaymentSchemaV1 = sr.Schema{
-	Type:   sr.TypeAvro,
-	Schema: PaymentSchemaTextV1,
-}
+func (PaymentV1) RecordName() string { return PaymentRecordName }
+
+var PaymentSchemaV1 = PaymentV1Codec.SRSchema()
 
 func PaymentV1Avro() avro.Schema {
-	s, err := avro.Parse(PaymentSchemaTextV1)
-	if err != nil {
-		err = fmt.Errorf(
-			"failed to parse PaymentSchemaTextV1, contact with package dev team: %w",
-			err,
-		)
-		panic(err)
-	}
-	return s
+	return PaymentV1Codec.Schema()
 }
 
 func PaymentV1AvroEncodeFn() func(v any) ([]byte, error) {
-	return func(v any) ([]byte, error) {
-		s := PaymentV1Avro()
-		return avro.Marshal(s, v)
-	}
+	return PaymentV1Codec.EncodeFn()
 }
 
 func PaymentV1AvroDecodeFn() func([]byte, any) error {
-	return func(data []byte, v any) error {
-		s := PaymentV1Avro()
-		return avro.Unmarshal(s, data, v)
-	}
+	return PaymentV1Codec.DecodeFn()
 }
@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+var PaymentSchemaTextJSON = mustReadFile("json/payment.json")
+
+type paymentJSON struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+	CreatedAt   time.Time `json:"created_at"`
+	Description *string   `json:"description,omitempty"`
+}
+
+// PaymentJSONEncodeFn encodes PaymentV2 as the transactions.payment
+// JSON document, v must be PaymentV2 or *PaymentV2.
+func PaymentJSONEncodeFn() func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		p, err := paymentV2Of(v)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := p.AmountRat()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(paymentJSON{
+			ID:          p.ID,
+			Name:        p.Name,
+			Amount:      RatString(amount),
+			Currency:    p.Currency,
+			CreatedAt:   p.CreatedAt,
+			Description: p.Description,
+		})
+	}
+}
+
+// PaymentJSONDecodeFn decodes the transactions.payment
+// JSON document into *PaymentV2.
+func PaymentJSONDecodeFn() func([]byte, any) error {
+	return func(b []byte, v any) error {
+		p, ok := v.(*PaymentV2)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", v)
+		}
+
+		var pj paymentJSON
+		if err := json.Unmarshal(b, &pj); err != nil {
+			return err
+		}
+		amount, err := ParseRat(pj.Amount)
+		if err != nil {
+			return err
+		}
+
+		if pj.Currency == "" {
+			pj.Currency = CurrencyUnknown
+		}
+
+		*p = PaymentV2{
+			ID:          pj.ID,
+			Name:        pj.Name,
+			Amount:      amount,
+			Currency:    pj.Currency,
+			CreatedAt:   pj.CreatedAt,
+			Description: pj.Description,
+		}
+		return nil
+	}
+}
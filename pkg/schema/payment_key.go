@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// PaymentKeyRecordName is the fully qualified name of the payment key record.
+const PaymentKeyRecordName = "transactions.payment_key"
+
+var (
+	PaymentKeySchemaText      = mustReadFile("avro/payment_key.avsc")
+	PaymentKeySchemaTextProto = mustReadFile("proto/payment_key.proto")
+	PaymentKeySchemaTextJSON  = mustReadFile("json/payment_key.json")
+)
+
+var PaymentKeyCodec = MustRegister[PaymentKey](
+	DefaultRegistry, PaymentKeyRecordName, 1, PaymentKeySchemaText,
+)
+
+// PaymentKey is the record key, records of one payment share a partition.
+type PaymentKey struct {
+	ID string `avro:"id" json:"id"`
+}
+
+func (PaymentKey) RecordName() string { return PaymentKeyRecordName }
+
+func paymentKeyOf(v any) (PaymentKey, error) {
+	switch k := v.(type) {
+	case PaymentKey:
+		return k, nil
+	case *PaymentKey:
+		return *k, nil
+	default:
+		return PaymentKey{}, fmt.Errorf("unexpected type %T", v)
+	}
+}
+
+func PaymentKeyProtoEncodeFn() func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		k, err := paymentKeyOf(v)
+		if err != nil {
+			return nil, err
+		}
+		return appendProtoString(nil, protoFieldID, k.ID), nil
+	}
+}
+
+func PaymentKeyProtoDecodeFn() func([]byte, any) error {
+	return func(b []byte, v any) error {
+		k, ok := v.(*PaymentKey)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", v)
+		}
+		*k = PaymentKey{}
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			if num == protoFieldID && typ == protowire.BytesType {
+				id, n := protowire.ConsumeString(b)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				b = b[n:]
+				k.ID = id
+				continue
+			}
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+		return nil
+	}
+}
+
+func PaymentKeyJSONEncodeFn() func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		k, err := paymentKeyOf(v)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(k)
+	}
+}
+
+func PaymentKeyJSONDecodeFn() func([]byte, any) error {
+	return func(b []byte, v any) error {
+		return json.Unmarshal(b, v)
+	}
+}
@@ -0,0 +1,134 @@
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var PaymentSchemaTextProto = mustReadFile("proto/payment.proto")
+
+// Field numbers of the transactions.Payment message.
+const (
+	protoFieldID protowire.Number = iota + 1
+	protoFieldName
+	protoFieldAmount
+	protoFieldCurrency
+	protoFieldCreatedAt
+	protoFieldDescription
+)
+
+// PaymentProtoEncodeFn encodes PaymentV2 as the transactions.Payment
+// protobuf message, v must be PaymentV2 or *PaymentV2.
+func PaymentProtoEncodeFn() func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		p, err := paymentV2Of(v)
+		if err != nil {
+			return nil, err
+		}
+		amount, err := p.AmountRat()
+		if err != nil {
+			return nil, err
+		}
+
+		var b []byte
+		b = appendProtoString(b, protoFieldID, p.ID)
+		b = appendProtoString(b, protoFieldName, p.Name)
+		b = appendProtoString(b, protoFieldAmount, RatString(amount))
+		b = appendProtoString(b, protoFieldCurrency, p.Currency)
+		if ms := p.CreatedAt.UnixMilli(); ms != 0 {
+			b = protowire.AppendTag(b, protoFieldCreatedAt, protowire.VarintType)
+			b = protowire.AppendVarint(b, uint64(ms))
+		}
+		if p.Description != nil {
+			b = protowire.AppendTag(b, protoFieldDescription, protowire.BytesType)
+			b = protowire.AppendString(b, *p.Description)
+		}
+		return b, nil
+	}
+}
+
+// PaymentProtoDecodeFn decodes the transactions.Payment
+// protobuf message into *PaymentV2.
+func PaymentProtoDecodeFn() func([]byte, any) error {
+	return func(b []byte, v any) error {
+		p, ok := v.(*PaymentV2)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", v)
+		}
+		*p = PaymentV2{Currency: CurrencyUnknown, CreatedAt: time.UnixMilli(0)}
+
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+
+			if typ == protowire.BytesType && num <= protoFieldDescription {
+				s, n := protowire.ConsumeString(b)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				b = b[n:]
+				if err := p.setProtoString(num, s); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if typ == protowire.VarintType && num == protoFieldCreatedAt {
+				ms, n := protowire.ConsumeVarint(b)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				b = b[n:]
+				p.CreatedAt = time.UnixMilli(int64(ms))
+				continue
+			}
+
+			// unknown fields are skipped as protobuf requires
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+
+		if p.Amount == nil {
+			p.Amount = new(big.Rat)
+		}
+		return nil
+	}
+}
+
+func (p *PaymentV2) setProtoString(num protowire.Number, s string) error {
+	switch num {
+	case protoFieldID:
+		p.ID = s
+	case protoFieldName:
+		p.Name = s
+	case protoFieldAmount:
+		amount, err := ParseRat(s)
+		if err != nil {
+			return err
+		}
+		p.Amount = amount
+	case protoFieldCurrency:
+		p.Currency = s
+	case protoFieldDescription:
+		p.Description = &s
+	}
+	return nil
+}
+
+func appendProtoString(b []byte, num protowire.Number, s string) []byte {
+	// proto3 does not write scalar fields with default values
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
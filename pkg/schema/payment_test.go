@@ -3,8 +3,12 @@
 package schema
 
 import (
+	"math/big"
 	"testing"
+	"time"
 
+	"github.com/hamba/avro/v2"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -14,4 +18,64 @@ func TestPayment(t *testing.T) {
 			_ = PaymentV1Avro()
 		})
 	})
+
+	t.Run("AvroParseV2", func(t *testing.T) {
+		require.NotPanics(t, func() {
+			_ = PaymentV2Avro()
+		})
+	})
+
+	t.Run("V2BackwardCompatible", func(t *testing.T) {
+		sc := avro.NewSchemaCompatibility()
+		err := sc.Compatible(PaymentV2Avro(), PaymentV1Avro())
+		require.NoError(t, err)
+	})
+
+	t.Run("V2ReadsV1Data", func(t *testing.T) {
+		data, err := PaymentV1AvroEncodeFn()(PaymentV1{"id", "name", 10.25})
+		require.NoError(t, err)
+
+		sc := avro.NewSchemaCompatibility()
+		resolved, err := sc.Resolve(PaymentV2Avro(), PaymentV1Avro())
+		require.NoError(t, err)
+
+		var p PaymentV2
+		require.NoError(t, avro.Unmarshal(resolved, data, &p))
+		amount, err := p.AmountRat()
+		require.NoError(t, err)
+		assert.Equal(t, "10.25", amount.FloatString(2))
+		assert.Equal(t, CurrencyUnknown, p.Currency)
+		assert.Nil(t, p.Description)
+	})
+
+	t.Run("V2RoundTrip", func(t *testing.T) {
+		desc := "coffee"
+		want := PaymentV2{
+			ID:          "id",
+			Name:        "name",
+			Amount:      big.NewRat(1999, 100),
+			Currency:    "EUR",
+			CreatedAt:   time.UnixMilli(1_700_000_000_123).UTC(),
+			Description: &desc,
+		}
+		data, err := PaymentV2AvroEncodeFn()(want)
+		require.NoError(t, err)
+
+		var got PaymentV2
+		require.NoError(t, PaymentV2AvroDecodeFn()(data, &got))
+		amount, err := got.AmountRat()
+		require.NoError(t, err)
+		assert.Equal(t, "19.99", amount.FloatString(2))
+		assert.Equal(t, want.Currency, got.Currency)
+		assert.True(t, want.CreatedAt.Equal(got.CreatedAt))
+		assert.Equal(t, desc, *got.Description)
+	})
+
+	t.Run("MigrateV1", func(t *testing.T) {
+		p := MigrateV1(PaymentV1{"id", "name", 0.1})
+		amount, err := p.AmountRat()
+		require.NoError(t, err)
+		assert.Equal(t, big.NewRat(1, 10), amount)
+		assert.Equal(t, CurrencyUnknown, p.Currency)
+	})
 }
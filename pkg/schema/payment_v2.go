@@ -0,0 +1,115 @@
+package schema
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// PaymentSchemaTextV2 is backward compatible with PaymentSchemaTextV1:
+// amount keeps the V1 double as the second union branch and all
+// new fields have defaults.
+var PaymentSchemaTextV2 = mustReadFile("avro/payment_v2.avsc")
+
+var PaymentV2Codec = MustRegister[PaymentV2](
+	DefaultRegistry, PaymentSubject, 2, PaymentSchemaTextV2,
+)
+
+// CurrencyUnknown is the currency of payments migrated from V1.
+const CurrencyUnknown = "XXX"
+
+// amountScale is the scale of the V2 amount decimal.
+const amountScale = 4
+
+type PaymentV2 struct {
+	ID   string `avro:"id"`
+	Name string `avro:"name"`
+	// Amount is *big.Rat, float64 is only met
+	// when V1 data is resolved with the V2 schema.
+	Amount      any       `avro:"amount"`
+	Currency    string    `avro:"currency"`
+	CreatedAt   time.Time `avro:"created_at"`
+	Description *string   `avro:"description"`
+}
+
+func (PaymentV2) RecordName() string { return PaymentRecordName }
+
+// AmountRat returns the amount as a rational number
+// regardless of the union branch it was decoded from.
+func (p PaymentV2) AmountRat() (*big.Rat, error) {
+	switch a := p.Amount.(type) {
+	case *big.Rat:
+		return a, nil
+	case float64:
+		return FloatToRat(a), nil
+	default:
+		return nil, fmt.Errorf("unexpected amount type %T", p.Amount)
+	}
+}
+
+// FloatToRat converts a float to the shortest decimal
+// that represents it, so 0.1 stays 1/10.
+func FloatToRat(f float64) *big.Rat {
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	return r
+}
+
+// RatString formats a rational number as
+// a decimal with at most amountScale fraction digits.
+func RatString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	s := strings.TrimRight(r.FloatString(amountScale), "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// ParseRat parses a decimal number like "123.45".
+func ParseRat(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid decimal %q", s)
+	}
+	return r, nil
+}
+
+func paymentV2Of(v any) (PaymentV2, error) {
+	switch p := v.(type) {
+	case PaymentV2:
+		return p, nil
+	case *PaymentV2:
+		return *p, nil
+	default:
+		return PaymentV2{}, fmt.Errorf("unexpected type %T", v)
+	}
+}
+
+// MigrateV1 upgrades V1 data to V2. Values that V1 does not carry are
+// set to the V2 schema defaults.
+func MigrateV1(p PaymentV1) PaymentV2 {
+	return PaymentV2{
+		ID:        p.ID,
+		Name:      p.Name,
+		Amount:    FloatToRat(p.Amount),
+		Currency:  CurrencyUnknown,
+		CreatedAt: time.UnixMilli(0),
+	}
+}
+
+var PaymentSchemaV2 = PaymentV2Codec.SRSchema()
+
+func PaymentV2Avro() avro.Schema {
+	return PaymentV2Codec.Schema()
+}
+
+func PaymentV2AvroEncodeFn() func(v any) ([]byte, error) {
+	return PaymentV2Codec.EncodeFn()
+}
+
+func PaymentV2AvroDecodeFn() func([]byte, any) error {
+	return PaymentV2Codec.DecodeFn()
+}
@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// RefundRecordName is the fully qualified name of the refund record,
+// it is also the registry key of the refund schema.
+const RefundRecordName = "transactions.refund"
+
+var (
+	RefundSchemaText      = mustReadFile("avro/refund.avsc")
+	RefundSchemaTextProto = mustReadFile("proto/refund.proto")
+	RefundSchemaTextJSON  = mustReadFile("json/refund.json")
+)
+
+var RefundCodec = MustRegister[Refund](
+	DefaultRegistry, RefundRecordName, 1, RefundSchemaText,
+)
+
+// Refund returns the amount of a payment to the payer, it shares
+// a topic with the payment records when a record subject name
+// strategy is used.
+type Refund struct {
+	// ID is the ID of the refunded payment.
+	ID        string    `avro:"id"`
+	Name      string    `avro:"name"`
+	Amount    *big.Rat  `avro:"amount"`
+	Currency  string    `avro:"currency"`
+	CreatedAt time.Time `avro:"created_at"`
+	Reason    *string   `avro:"reason"`
+}
+
+func (Refund) RecordName() string { return RefundRecordName }
+
+// Payment returns the refunded payment in the latest payment schema.
+func (r Refund) Payment() PaymentV2 {
+	amount := r.Amount
+	if amount == nil {
+		amount = new(big.Rat)
+	}
+	return PaymentV2{
+		ID:          r.ID,
+		Name:        r.Name,
+		Amount:      amount,
+		Currency:    r.Currency,
+		CreatedAt:   r.CreatedAt,
+		Description: r.Reason,
+	}
+}
+
+func refundOf(v any) (Refund, error) {
+	switch r := v.(type) {
+	case Refund:
+		return r, nil
+	case *Refund:
+		return *r, nil
+	default:
+		return Refund{}, fmt.Errorf("unexpected type %T", v)
+	}
+}
+
+// RefundProtoEncodeFn encodes Refund as the transactions.Refund
+// protobuf message, it has the field numbers of transactions.Payment.
+func RefundProtoEncodeFn() func(v any) ([]byte, error) {
+	encode := PaymentProtoEncodeFn()
+	return func(v any) ([]byte, error) {
+		r, err := refundOf(v)
+		if err != nil {
+			return nil, err
+		}
+		return encode(r.Payment())
+	}
+}
+
+// RefundProtoDecodeFn decodes the transactions.Refund
+// protobuf message into *Refund.
+func RefundProtoDecodeFn() func([]byte, any) error {
+	decode := PaymentProtoDecodeFn()
+	return func(b []byte, v any) error {
+		r, ok := v.(*Refund)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", v)
+		}
+		var p PaymentV2
+		if err := decode(b, &p); err != nil {
+			return err
+		}
+		return r.fromPayment(p)
+	}
+}
+
+type refundJSON struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Amount    string    `json:"amount"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    *string   `json:"reason,omitempty"`
+}
+
+// RefundJSONEncodeFn encodes Refund as the
+// transactions.refund JSON document.
+func RefundJSONEncodeFn() func(v any) ([]byte, error) {
+	return func(v any) ([]byte, error) {
+		r, err := refundOf(v)
+		if err != nil {
+			return nil, err
+		}
+		amount := r.Amount
+		if amount == nil {
+			amount = new(big.Rat)
+		}
+		return json.Marshal(refundJSON{
+			ID:        r.ID,
+			Name:      r.Name,
+			Amount:    RatString(amount),
+			Currency:  r.Currency,
+			CreatedAt: r.CreatedAt,
+			Reason:    r.Reason,
+		})
+	}
+}
+
+// RefundJSONDecodeFn decodes the transactions.refund
+// JSON document into *Refund.
+func RefundJSONDecodeFn() func([]byte, any) error {
+	return func(b []byte, v any) error {
+		r, ok := v.(*Refund)
+		if !ok {
+			return fmt.Errorf("unexpected type %T", v)
+		}
+
+		var rj refundJSON
+		if err := json.Unmarshal(b, &rj); err != nil {
+			return err
+		}
+		amount, err := ParseRat(rj.Amount)
+		if err != nil {
+			return err
+		}
+
+		if rj.Currency == "" {
+			rj.Currency = CurrencyUnknown
+		}
+
+		*r = Refund{
+			ID:        rj.ID,
+			Name:      rj.Name,
+			Amount:    amount,
+			Currency:  rj.Currency,
+			CreatedAt: rj.CreatedAt,
+			Reason:    rj.Reason,
+		}
+		return nil
+	}
+}
+
+func (r *Refund) fromPayment(p PaymentV2) error {
+	amount, err := p.AmountRat()
+	if err != nil {
+		return err
+	}
+	*r = Refund{
+		ID:        p.ID,
+		Name:      p.Name,
+		Amount:    amount,
+		Currency:  p.Currency,
+		CreatedAt: p.CreatedAt,
+		Reason:    p.Description,
+	}
+	return nil
+}